@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"PromAI/pkg/metrics"
+)
+
+// checkStatusText 检查结果状态的显示文本
+var checkStatusText = map[string]string{
+	metrics.CheckOK:      "正常",
+	metrics.CheckWarning: "警告",
+	metrics.CheckFailed:  "失败",
+}
+
+// runQueryChecks 对每个指标执行一次查询并输出检查结果，有指标检查失败时返回 false
+func runQueryChecks(collector *metrics.Collector, w io.Writer) bool {
+	checks := collector.CheckQueries(context.Background())
+
+	counts := make(map[string]int)
+	for _, check := range checks {
+		counts[check.Status]++
+		writeQueryCheck(w, check)
+	}
+	fmt.Fprintf(w, "共检查 %d 个指标: 正常 %d, 警告 %d, 失败 %d\n",
+		len(checks), counts[metrics.CheckOK], counts[metrics.CheckWarning], counts[metrics.CheckFailed])
+	return counts[metrics.CheckFailed] == 0
+}
+
+// writeQueryCheck 输出单个指标的检查结果
+func writeQueryCheck(w io.Writer, check metrics.QueryCheck) {
+	fmt.Fprintf(w, "[%s] %s / %s (数据源: %s, 耗时: %v)\n",
+		checkStatusText[check.Status], check.Group, check.Metric, check.Datasource, check.Latency.Round(time.Millisecond))
+	fmt.Fprintf(w, "  PromQL: %s\n", check.Query)
+	if check.Error != "" {
+		fmt.Fprintf(w, "  错误: %s\n\n", check.Error)
+		return
+	}
+	fmt.Fprintf(w, "  结果类型: %s, 序列数: %d\n", check.ResultType, check.SeriesCount)
+	if len(check.MissingLabels) > 0 {
+		missing := make([]string, 0, len(check.MissingLabels))
+		for _, label := range check.MissingLabels {
+			missing = append(missing, fmt.Sprintf("%s (%d/%d)", label.Name, label.Series, check.SeriesCount))
+		}
+		fmt.Fprintf(w, "  缺失标签: %s\n", strings.Join(missing, ", "))
+	}
+	for _, sample := range check.Samples {
+		fmt.Fprintf(w, "  样例: %s\n", sample)
+	}
+	for _, warning := range check.Warnings {
+		fmt.Fprintf(w, "  Prometheus 警告: %s\n", warning)
+	}
+	for _, problem := range check.Problems {
+		fmt.Fprintf(w, "  问题: %s\n", problem)
+	}
+	fmt.Fprintln(w)
+}
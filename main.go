@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
@@ -9,7 +11,9 @@ import (
 	"os"
 	"time"
 
+	"PromAI/pkg/api"
 	"PromAI/pkg/config"
+	"PromAI/pkg/jobs"
 	"PromAI/pkg/metrics"
 	"PromAI/pkg/notify"
 	"PromAI/pkg/prometheus"
@@ -17,12 +21,11 @@ import (
 	"PromAI/pkg/status"
 	"PromAI/pkg/utils"
 
-	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v2"
 )
 
-// loadConfig 加载配置文件
-func loadConfig(path string) (*config.Config, error) {
+// loadConfig 加载并校验配置文件，校验错误带有行号
+func loadConfig(path string, opts config.ValidateOptions) (*config.Config, error) {
 	data, err := os.ReadFile(path) // 读取配置文件
 	if err != nil {
 		return nil, fmt.Errorf("reading config file: %w", err)
@@ -39,165 +42,227 @@ func loadConfig(path string) (*config.Config, error) {
 	} else {
 		log.Printf("使用配置文件中的 Prometheus URL: %s", config.PrometheusURL)
 	}
+
+	if err := validateConfig(&config, data, opts); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", path, err)
+	}
 	return &config, nil // 返回配置结构体
 }
 
-// setup 初始化应用程序
-func setup(configPath string) (*prometheus.Client, *config.Config, error) {
-	config, err := loadConfig(configPath)
+// validateConfig 校验配置，按配置文件内容为校验错误补充行号
+func validateConfig(cfg *config.Config, source []byte, opts config.ValidateOptions) error {
+	err := cfg.ValidateWith(opts)
+	var validationErrs config.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validationErrs.WithLines(source)
+	}
+	return err
+}
+
+// setup 加载并校验配置，创建数据源客户端和指标收集器，不修改全局状态
+func setup(configPath string, opts config.ValidateOptions) (*metrics.Collector, *config.Config, error) {
+	config, err := loadConfig(configPath, opts)
 	if err != nil {
 		return nil, nil, fmt.Errorf("loading config: %w", err)
 	}
 
-	client, err := prometheus.NewClient(config.PrometheusURL)
+	clients, err := prometheus.NewClients(config.AllDatasources())
 	if err != nil {
 		return nil, nil, fmt.Errorf("initializing Prometheus client: %w", err)
 	}
 
-	return client, config, nil
+	defaultClient, exists := clients[config.DefaultDatasource()]
+	if !exists {
+		return nil, nil, fmt.Errorf("no Prometheus datasource configured")
+	}
+
+	collector := metrics.NewCollector(defaultClient.API, config)
+	for name, client := range clients {
+		collector.AddDatasource(name, client.API)
+	}
+
+	return collector, config, nil
+}
+
+// applyGlobals 应用报告存储、PDF 导出等全局配置
+func applyGlobals(config *config.Config) {
+	// 历史报告快照
+	report.SetStore(report.NewFileStore(config.ReportStore.Dir))
+	report.SetPDFConfig(config.PDF.ReportConfig())
 }
 
 func main() {
 	configPath := flag.String("config", "config/config.yaml", "Path to configuration file")
 	port := flag.String("port", "8091", "Port to run the HTTP server on")
+	rerender := flag.String("rerender", "", "Re-render a stored report snapshot by id with the current template and exit")
+	watchInterval := flag.Duration("watch-interval", 10*time.Second, "Interval for polling the config file for changes, 0 disables watching")
+	validate := flag.Bool("validate", false, "Validate the configuration file and exit")
+	parseQueries := flag.Bool("parse-queries", false, "Also parse every PromQL expression with the Prometheus parser during validation")
+	checkQueries := flag.Bool("check-queries", false, "Run every configured query once against Prometheus, print the results and exit")
 	flag.Parse()
 
+	validateOptions := config.ValidateOptions{ParseQueries: *parseQueries}
+
+	// 只校验配置文件，错误逐行输出，有错误时以非零状态退出
+	if *validate {
+		if _, err := loadConfig(*configPath, validateOptions); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("配置校验通过: %s\n", *configPath)
+		return
+	}
+
+	// 对每个指标执行一次查询并输出结果，有指标失败时以非零状态退出
+	if *checkQueries {
+		collector, _, err := setup(*configPath, validateOptions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !runQueryChecks(collector, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	utils.SetGlobalPort(*port)
 
-	client, config, err := setup(*configPath)
+	svc := &service{configPath: *configPath, validateOptions: validateOptions}
+	// 巡检任务统一经任务队列执行，避免重复收集；执行时使用当前生效的收集器
+	svc.manager = jobs.NewManager(makeRunFunc(svc.collector))
+
+	rt, err := svc.load()
 	if err != nil {
 		log.Fatalf("Error setting up: %v", err)
 	}
 
-	collector := metrics.NewCollector(client.API, config)
-
-	// 设置定时任务
-	if config.CronSchedule != "" {
-		c := cron.New()
-		_, err := c.AddFunc(config.CronSchedule, func() {
-			data, err := collector.CollectMetrics()
-			if err != nil {
-				log.Printf("定时任务收集指标失败: %v", err)
-				return
-			}
-
-			reportFilePath, err := report.GenerateReport(*data)
-			if err != nil {
-				log.Printf("定时任务生成报告失败: %v", err)
-				return
-			}
-			log.Printf("定时任务成功生成报告: %s", reportFilePath)
-
-			if config.Notifications.Dingtalk.Enabled {
-				log.Printf("发送钉钉消息")
-				if err := notify.SendDingtalk(config.Notifications.Dingtalk, reportFilePath); err != nil {
-					log.Printf("发送钉钉消息失败: %v", err)
-				}
-			}
-
-			if config.Notifications.Email.Enabled {
-				log.Printf("发送邮件")
-				notify.SendEmail(config.Notifications.Email, reportFilePath)
-			}
-
-			// ✅ 新增：企业微信通知
-			if config.Notifications.Wecom.Enabled {
-				log.Printf("发送企业微信消息")
-				if err := notify.SendWeCom(config.Notifications.Wecom, reportFilePath); err != nil {
-					log.Printf("发送企业微信消息失败: %v", err)
-				}
-			}
-
-		})
-
+	// 使用当前模板重新渲染历史报告
+	if *rerender != "" {
+		applyGlobals(rt.config)
+		reportFilePath, err := report.RerenderReport(*rerender)
 		if err != nil {
-			log.Printf("设置定时任务失败: %v", err)
-		} else {
-			c.Start()
-			log.Printf("已启动定时任务，执行计划: %s", config.CronSchedule)
+			log.Fatalf("Error re-rendering report %s: %v", *rerender, err)
 		}
-	} else {
-		log.Printf("未配置定时任务，请手动触发生成报告")
+		log.Printf("报告已重新渲染: %s", reportFilePath)
+		return
 	}
-	if config.ReportCleanup.Enabled {
-		// 确定使用哪个计划
-		cleanupSchedule := config.ReportCleanup.CronSchedule
-		if cleanupSchedule == "" {
-			cleanupSchedule = config.CronSchedule
-		}
 
-		if cleanupSchedule != "" {
-			c := cron.New()
-			_, err := c.AddFunc(cleanupSchedule, func() {
-				if err := report.CleanupReports(config.ReportCleanup.MaxAge); err != nil {
-					log.Printf("报告清理失败: %v", err)
-					return
-				}
-				log.Printf("报告清理成功")
-			})
+	svc.api = api.NewServer(rt.config, svc.manager)
+	svc.api.SetReloader(func() error { return svc.reload("POST /api/reload") })
+	svc.activate(rt)
 
-			if err != nil {
-				log.Printf("设置清理定时任务失败: %v", err)
-			} else {
-				c.Start()
-				log.Printf("已启动清理定时任务，执行计划: %s", cleanupSchedule)
-			}
-		} else {
-			log.Printf("未配置任何定时任务计划，请手动清理报告")
-		}
-	}
+	// 配置文件变化或收到 SIGHUP 时重新加载
+	svc.watch(*watchInterval)
+	svc.handleSignals()
 
 	// 设置路由处理器
-	setupRoutes(collector, config)
+	setupRoutes(svc)
 
 	// 启动服务器
 	log.Printf("Starting server on port: %s with config: %s", *port, *configPath)
-	log.Printf("Prometheus URL: %s", config.PrometheusURL)
+	for _, ds := range rt.config.AllDatasources() {
+		log.Printf("Prometheus 数据源 [%s]: %s", ds.Name, ds.URL)
+	}
 	log.Printf("获取报告地址: http://localhost:%s/getreport", *port)
 	log.Printf("健康看板地址: http://localhost:%s/status", *port)
+	log.Printf("API 地址: http://localhost:%s/api/v1/reports", *port)
 	if err := http.ListenAndServe(":"+*port, nil); err != nil {
 		log.Fatalf("Error starting HTTP server: %v", err)
 	}
 }
 
-// setupRoutes 设置 HTTP 路由
-func setupRoutes(collector *metrics.Collector, config *config.Config) {
+// sendNotifications 按配置发送报告通知
+func sendNotifications(config *config.Config, reportFilePath string) {
+	if config.Notifications.Dingtalk.Enabled {
+		log.Printf("发送钉钉消息")
+		if err := notify.SendDingtalk(config.Notifications.Dingtalk, reportFilePath); err != nil {
+			log.Printf("发送钉钉消息失败: %v", err)
+		}
+	}
+
+	if config.Notifications.Email.Enabled {
+		log.Printf("发送邮件")
+		notify.SendEmail(config.Notifications.Email, reportFilePath)
+	}
+
+	// ✅ 新增：企业微信通知
+	if config.Notifications.Wecom.Enabled {
+		log.Printf("发送企业微信消息")
+		if err := notify.SendWeCom(config.Notifications.Wecom, reportFilePath); err != nil {
+			log.Printf("发送企业微信消息失败: %v", err)
+		}
+	}
+}
+
+// setupRoutes 设置 HTTP 路由，处理器每次请求时读取当前生效的组件
+func setupRoutes(svc *service) {
 	// 设置报告生成路由
-	http.HandleFunc("/getreport", makeReportHandler(collector))
+	http.HandleFunc("/getreport", makeReportHandler(svc.manager))
+
+	// 报告生成等待页面
+	http.HandleFunc("GET /runs/{id}", makeRunPageHandler(svc.manager))
 
 	// 设置静态文件服务
 	http.Handle("/reports/", http.StripPrefix("/reports/", http.FileServer(http.Dir("reports"))))
 
 	// 设置状态页面路由
-	http.HandleFunc("/status", makeStatusHandler(collector.Client, config))
+	http.HandleFunc("/status", makeStatusHandler(svc.statusCache))
+	http.HandleFunc("GET /status/metric/{name...}", makeStatusMetricHandler(svc.statusCache))
 
+	// 设置 JSON API 路由
+	svc.api.Register(http.DefaultServeMux)
 }
 
-// makeReportHandler 创建报告处理器
-func makeReportHandler(collector *metrics.Collector) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		data, err := collector.CollectMetrics()
+// makeRunFunc 创建收集指标并生成报告的执行函数，每次执行时使用 collector 返回的收集器
+func makeRunFunc(collector func() *metrics.Collector) jobs.RunFunc {
+	return func(ctx context.Context, progress metrics.ProgressFunc) (string, error) {
+		data, err := collector().CollectMetricsWithProgress(ctx, progress)
 		if err != nil {
-			http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
-			log.Printf("Error collecting metrics: %v", err)
+			return "", fmt.Errorf("collecting metrics: %w", err)
+		}
+		return report.GenerateReport(*data)
+	}
+}
+
+// makeReportHandler 创建报告处理器：提交巡检任务后跳转到等待页面
+func makeReportHandler(manager *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, _ := manager.Submit("web", nil)
+		http.Redirect(w, r, "/runs/"+job.ID, http.StatusSeeOther)
+	}
+}
+
+// makeRunPageHandler 创建报告生成等待页面处理器，任务完成后跳转到报告
+func makeRunPageHandler(manager *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, exists := manager.Get(r.PathValue("id"))
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		if job.Status == jobs.StatusSucceeded {
+			http.Redirect(w, r, "/"+job.ReportFile, http.StatusSeeOther)
 			return
 		}
 
-		reportFilePath, err := report.GenerateReport(*data)
+		tmpl, err := template.ParseFiles("templates/run.html")
 		if err != nil {
-			http.Error(w, "Failed to generate report", http.StatusInternalServerError)
-			log.Printf("Error generating report: %v", err)
+			http.Error(w, "Failed to parse template", http.StatusInternalServerError)
+			log.Printf("Error parsing template: %v", err)
 			return
 		}
-
-		http.Redirect(w, r, "/"+reportFilePath, http.StatusSeeOther)
+		if err := tmpl.Execute(w, job); err != nil {
+			http.Error(w, "Failed to render template", http.StatusInternalServerError)
+			log.Printf("Error rendering template: %v", err)
+		}
 	}
 }
 
-// makeStatusHandler 创建状态页面处理器
-func makeStatusHandler(client metrics.PrometheusAPI, config *config.Config) http.HandlerFunc {
+// makeStatusHandler 创建状态页面处理器，数据来自健康看板缓存
+func makeStatusHandler(statusCache func() *status.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		data, err := status.CollectMetricStatus(client, config)
+		data, err := statusCache().Data()
 		if err != nil {
 			http.Error(w, "Failed to collect status data", http.StatusInternalServerError)
 			log.Printf("Error collecting status data: %v", err)
@@ -227,3 +292,40 @@ func makeStatusHandler(client metrics.PrometheusAPI, config *config.Config) http
 		}
 	}
 }
+
+// makeStatusMetricHandler 创建单个指标的下钻页面处理器，?date= 指定热力图显示的日期
+func makeStatusMetricHandler(statusCache func() *status.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := statusCache().Detail(r.PathValue("name"), r.URL.Query().Get("date"))
+		if errors.Is(err, status.ErrMetricNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to collect status data", http.StatusInternalServerError)
+			log.Printf("Error collecting status data: %v", err)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"now": time.Now,
+			"date": func(format string, t time.Time) string {
+				return t.Format(format)
+			},
+		}
+
+		tmpl := template.New("status_metric.html").Funcs(funcMap)
+		tmpl, err = tmpl.ParseFiles("templates/status_metric.html")
+		if err != nil {
+			http.Error(w, "Failed to parse template", http.StatusInternalServerError)
+			log.Printf("Error parsing template: %v", err)
+			return
+		}
+
+		if err := tmpl.Execute(w, detail); err != nil {
+			http.Error(w, "Failed to render template", http.StatusInternalServerError)
+			log.Printf("Error rendering template: %v", err)
+			return
+		}
+	}
+}
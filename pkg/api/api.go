@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/jobs"
+	"PromAI/pkg/report"
+)
+
+// Server 提供 /api/v1 JSON 接口
+type Server struct {
+	mu       sync.RWMutex
+	config   *config.Config
+	jobs     *jobs.Manager
+	reloader func() error
+}
+
+// NewServer 创建 API 服务，巡检任务通过 jobs.Manager 排队执行
+func NewServer(config *config.Config, manager *jobs.Manager) *Server {
+	return &Server{
+		config: config,
+		jobs:   manager,
+	}
+}
+
+// SetConfig 替换当前配置，重新加载配置后调用
+func (s *Server) SetConfig(config *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+// SetReloader 设置 POST /api/reload 调用的重新加载函数，未设置时该接口返回 501
+func (s *Server) SetReloader(reload func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloader = reload
+}
+
+func (s *Server) currentConfig() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Register 在 mux 上注册 /api/v1 路由及 /api/reload
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/reports", s.listReports)
+	mux.HandleFunc("GET /api/v1/reports/{id}", s.getReport)
+	mux.HandleFunc("POST /api/v1/runs", s.createRun)
+	mux.HandleFunc("GET /api/v1/runs/{id}", s.getRun)
+	mux.HandleFunc("GET /api/v1/runs/{id}/events", s.streamRun)
+	mux.HandleFunc("GET /api/v1/metrics", s.listMetrics)
+	mux.HandleFunc("POST /api/reload", s.reload)
+}
+
+// listReports 列出历史报告，按时间倒序
+func (s *Server) listReports(w http.ResponseWriter, r *http.Request) {
+	store := report.Store()
+	if store == nil {
+		writeError(w, http.StatusServiceUnavailable, "report store not configured")
+		return
+	}
+	metas, err := store.List()
+	if err != nil {
+		log.Printf("API 列出报告失败: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to list reports")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reports": metas})
+}
+
+// getReport 返回单个报告的结构化数据，id 为 latest 时返回最近一次报告
+func (s *Server) getReport(w http.ResponseWriter, r *http.Request) {
+	store := report.Store()
+	if store == nil {
+		writeError(w, http.StatusServiceUnavailable, "report store not configured")
+		return
+	}
+
+	id := r.PathValue("id")
+	var (
+		snapshot *report.Snapshot
+		err      error
+	)
+	if id == "latest" {
+		snapshot, err = store.Latest()
+	} else {
+		snapshot, err = store.Load(id)
+	}
+	if errors.Is(err, report.ErrSnapshotNotFound) {
+		writeError(w, http.StatusNotFound, "report not found")
+		return
+	}
+	if err != nil {
+		log.Printf("API 读取报告 %s 失败: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "failed to load report")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// createRun 异步触发一次巡检，立即返回任务编号；已有巡检在执行时返回该任务
+func (s *Server) createRun(w http.ResponseWriter, r *http.Request) {
+	job, _ := s.jobs.Submit("api", nil)
+	w.Header().Set("Location", "/api/v1/runs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// getRun 查询巡检任务状态
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// streamRun 以 SSE 推送巡检任务进度，每个指标组完成时推送一次，结束时发送 done 事件
+func (s *Server) streamRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	updates, cancel, exists := s.jobs.Subscribe(id)
+	if !exists {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	defer cancel()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case job, ok := <-updates:
+			if !ok {
+				// 订阅通道关闭时可能丢弃了中间状态，以最终状态为准
+				job, _ = s.jobs.Get(id)
+				writeEvent(w, "done", job)
+				flusher.Flush()
+				return
+			}
+			writeEvent(w, "progress", job)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("API 事件编码失败: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// listMetrics 返回当前配置中的指标目录
+func (s *Server) listMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"groups": buildCatalog(s.currentConfig())})
+}
+
+// reload 重新加载配置文件，校验失败时继续使用旧配置并返回 422
+func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	reload := s.reloader
+	s.mu.RUnlock()
+	if reload == nil {
+		writeError(w, http.StatusNotImplemented, "reload not supported")
+		return
+	}
+	if err := reload(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("API 响应编码失败: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/jobs"
+	"PromAI/pkg/metrics"
+	"PromAI/pkg/report"
+)
+
+func newTestServer(t *testing.T, run jobs.RunFunc) *httptest.Server {
+	t.Helper()
+
+	store := report.NewFileStore(t.TempDir())
+	report.SetStore(store)
+	t.Cleanup(func() { report.SetStore(report.NewFileStore("reports")) })
+
+	if err := store.Save(&report.Snapshot{
+		ID:         "20240101_080000",
+		Project:    "测试项目",
+		ReportFile: "reports/inspection_report_20240101_080000.html",
+		Data:       report.ReportData{Project: "测试项目"},
+	}); err != nil {
+		t.Fatalf("保存快照失败: %v", err)
+	}
+
+	cfg := &config.Config{
+		PrometheusURL: "http://localhost:9090",
+		MetricTypes: []config.MetricType{{
+			Type: "基础资源使用情况",
+			Metrics: []config.MetricConfig{{
+				Name:   "CPU使用率",
+				Query:  "cpu_usage",
+				Unit:   "%",
+				Labels: config.Labels{{Name: "instance", Alias: "实例"}},
+			}},
+		}},
+	}
+
+	mux := http.NewServeMux()
+	NewServer(cfg, jobs.NewManager(run)).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("请求 %s 失败: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestReportsAndMetricsEndpoints(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	var list struct {
+		Reports []report.SnapshotMeta `json:"reports"`
+	}
+	if code := getJSON(t, server.URL+"/api/v1/reports", &list); code != http.StatusOK {
+		t.Fatalf("列出报告返回 %d", code)
+	}
+	if len(list.Reports) != 1 || list.Reports[0].ID != "20240101_080000" {
+		t.Errorf("报告列表不正确: %+v", list.Reports)
+	}
+
+	var snapshot report.Snapshot
+	if code := getJSON(t, server.URL+"/api/v1/reports/latest", &snapshot); code != http.StatusOK {
+		t.Fatalf("读取最近报告返回 %d", code)
+	}
+	if snapshot.Data.Project != "测试项目" {
+		t.Errorf("报告内容不正确: %+v", snapshot)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, server.URL+"/api/v1/reports/missing", &errResp); code != http.StatusNotFound {
+		t.Errorf("不存在的报告应返回 404，实际 %d", code)
+	}
+
+	var catalog struct {
+		Groups []CatalogGroup `json:"groups"`
+	}
+	if code := getJSON(t, server.URL+"/api/v1/metrics", &catalog); code != http.StatusOK {
+		t.Fatalf("指标目录返回 %d", code)
+	}
+	if len(catalog.Groups) != 1 || catalog.Groups[0].Metrics[0].Datasource != "default" {
+		t.Errorf("指标目录不正确: %+v", catalog.Groups)
+	}
+}
+
+func TestRunLifecycle(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestServer(t, func(ctx context.Context, progress metrics.ProgressFunc) (string, error) {
+		<-release
+		progress(metrics.Progress{Group: "基础资源使用情况", GroupsDone: 1, GroupsTotal: 1, MetricsDone: 1, MetricsTotal: 1})
+		return "reports/inspection_report_20240102_080000.html", nil
+	})
+
+	resp, err := http.Post(server.URL+"/api/v1/runs", "application/json", nil)
+	if err != nil {
+		t.Fatalf("触发运行失败: %v", err)
+	}
+	var run jobs.Job
+	json.NewDecoder(resp.Body).Decode(&run)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || run.ID == "" {
+		t.Fatalf("触发运行应返回 202 和运行编号，实际 %d %+v", resp.StatusCode, run)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for run.Status != jobs.StatusSucceeded && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		getJSON(t, server.URL+"/api/v1/runs/"+run.ID, &run)
+	}
+	if run.Status != jobs.StatusSucceeded || run.ReportID != "20240102_080000" || len(run.CompletedGroups) != 1 {
+		t.Errorf("运行结果不正确: %+v", run)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, server.URL+"/api/v1/runs/unknown", &errResp); code != http.StatusNotFound {
+		t.Errorf("不存在的运行应返回 404，实际 %d", code)
+	}
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	cfg := &config.Config{PrometheusURL: "http://localhost:9090"}
+	server := NewServer(cfg, jobs.NewManager(nil))
+	mux := http.NewServeMux()
+	server.Register(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	post := func() int {
+		resp, err := http.Post(ts.URL+"/api/reload", "application/json", nil)
+		if err != nil {
+			t.Fatalf("请求重新加载失败: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post(); code != http.StatusNotImplemented {
+		t.Errorf("未设置重新加载函数时应返回 501，实际 %d", code)
+	}
+
+	// 校验失败时返回 422，配置保持不变
+	server.SetReloader(func() error { return errors.New("invalid config") })
+	if code := post(); code != http.StatusUnprocessableEntity {
+		t.Errorf("校验失败应返回 422，实际 %d", code)
+	}
+
+	reloaded := &config.Config{
+		PrometheusURL: "http://localhost:9090",
+		MetricTypes:   []config.MetricType{{Type: "新分组", Metrics: []config.MetricConfig{{Name: "内存使用率", Query: "mem_usage"}}}},
+	}
+	server.SetReloader(func() error {
+		server.SetConfig(reloaded)
+		return nil
+	})
+	if code := post(); code != http.StatusOK {
+		t.Errorf("重新加载成功应返回 200，实际 %d", code)
+	}
+
+	var catalog struct {
+		Groups []CatalogGroup `json:"groups"`
+	}
+	getJSON(t, ts.URL+"/api/v1/metrics", &catalog)
+	if len(catalog.Groups) != 1 || catalog.Groups[0].Type != "新分组" {
+		t.Errorf("重新加载后指标目录应使用新配置: %+v", catalog.Groups)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import "PromAI/pkg/config"
+
+// CatalogGroup 指标目录中的一个分组
+type CatalogGroup struct {
+	Type    string          `json:"type"`
+	Metrics []CatalogMetric `json:"metrics"`
+}
+
+// CatalogMetric 指标目录中的一个指标
+type CatalogMetric struct {
+	Name          string         `json:"name"`
+	Type          string         `json:"type,omitempty"`
+	Description   string         `json:"description"`
+	Query         string         `json:"query"`
+	Datasource    string         `json:"datasource"`
+	Role          string         `json:"role,omitempty"`
+	Threshold     float64        `json:"threshold"`
+	ThresholdType string         `json:"threshold_type"`
+	Unit          string         `json:"unit"`
+	Labels        []CatalogLabel `json:"labels"`
+}
+
+// CatalogLabel 指标展示的标签及别名
+type CatalogLabel struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+// buildCatalog 按配置顺序生成指标目录
+func buildCatalog(cfg *config.Config) []CatalogGroup {
+	groups := make([]CatalogGroup, 0, len(cfg.MetricTypes))
+	for _, metricType := range cfg.MetricTypes {
+		group := CatalogGroup{
+			Type:    metricType.Type,
+			Metrics: make([]CatalogMetric, 0, len(metricType.Metrics)),
+		}
+		for _, metric := range metricType.Metrics {
+			labels := make([]CatalogLabel, 0, len(metric.Labels))
+			for _, label := range metric.Labels {
+				labels = append(labels, CatalogLabel{Name: label.Name, Alias: label.Alias})
+			}
+
+			group.Metrics = append(group.Metrics, CatalogMetric{
+				Name:          metric.Name,
+				Type:          metric.Type,
+				Description:   metric.Description,
+				Query:         metric.Query,
+				Datasource:    cfg.DatasourceFor(metricType, metric),
+				Role:          metric.Role,
+				Threshold:     metric.Threshold,
+				ThresholdType: metric.ThresholdType,
+				Unit:          metric.Unit,
+				Labels:        labels,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
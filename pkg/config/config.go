@@ -1,17 +1,39 @@
 package config
 
-import "PromAI/pkg/notify"
+import (
+	"PromAI/pkg/notify"
+	"PromAI/pkg/prometheus"
+	"PromAI/pkg/report"
+	"PromAI/pkg/threshold"
+)
 
 type Config struct {
-	PrometheusURL string       `yaml:"prometheus_url"`
-	MetricTypes   []MetricType `yaml:"metric_types"`
-	ProjectName   string       `yaml:"project_name"`
-	CronSchedule  string       `yaml:"cron_schedule"`
+	PrometheusURL string                        `yaml:"prometheus_url"`
+	Datasources   []prometheus.DatasourceConfig `yaml:"datasources"` // 多个 Prometheus/Thanos 数据源
+	MetricTypes   []MetricType                  `yaml:"metric_types"`
+	ProjectName   string                        `yaml:"project_name"`
+	CronSchedule  string                        `yaml:"cron_schedule"`
 	ReportCleanup struct {
 		Enabled      bool   `yaml:"enabled"`
 		MaxAge       int    `yaml:"max_age"`
 		CronSchedule string `yaml:"cron_schedule"`
 	} `yaml:"report_cleanup"`
+	// 历史报告快照存储
+	ReportStore report.StoreConfig `yaml:"report_store"`
+	// 与上一次巡检结果对比
+	Comparison report.ComparisonConfig `yaml:"comparison"`
+	// PDF 导出
+	PDF PDFConfig `yaml:"pdf"`
+	// 指标收集配置
+	Collection struct {
+		Concurrency  int      `yaml:"concurrency"`   // 并发查询数
+		Timeout      Duration `yaml:"timeout"`       // 整体收集超时时间
+		QueryTimeout Duration `yaml:"query_timeout"` // 单个查询默认超时时间
+	} `yaml:"collection"`
+	// 服务健康看板
+	Status StatusConfig `yaml:"status"`
+	// 主机资源概览使用的标签
+	HostSummary   report.HostSummaryConfig `yaml:"host_summary"`
 	Notifications struct {
 		Dingtalk notify.DingtalkConfig `yaml:"dingtalk"`
 		Email    notify.EmailConfig    `yaml:"email"`
@@ -21,22 +43,119 @@ type Config struct {
 }
 
 type MetricType struct {
-	Type    string         `yaml:"type"`
-	Metrics []MetricConfig `yaml:"metrics"`
+	Type       string         `yaml:"type"`
+	Datasource string         `yaml:"datasource,omitempty"` // 该组指标默认使用的数据源
+	Metrics    []MetricConfig `yaml:"metrics"`
 }
 
 type MetricConfig struct {
-	Name          string            `yaml:"name"`
-	Type          string            `yaml:"type"`
-	ShowInTable   *bool             `yaml:"show_in_table,omitempty"`
-	Description   string            `yaml:"description"`
-	Query         string            `yaml:"query"`
-	Threshold     float64           `yaml:"threshold"`
-	Unit          string            `yaml:"unit"`
-	Labels        map[string]string `yaml:"labels"`
-	ThresholdType string            `yaml:"threshold_type"`
+	Name          string  `yaml:"name"`
+	Type          string  `yaml:"type"`
+	ShowInTable   *bool   `yaml:"show_in_table,omitempty"`
+	Description   string  `yaml:"description"`
+	Query         string  `yaml:"query"`
+	Threshold     float64 `yaml:"threshold"`
+	Unit          string  `yaml:"unit"`
+	Labels        Labels  `yaml:"labels"` // 标签及别名，按书写顺序输出列
+	ThresholdType string  `yaml:"threshold_type"`
 	// 新增单位换算配置
-	ScaleFactor   *float64          `yaml:"scale_factor,omitempty"`   // 缩放因子，用于附加的数值调整
-	TargetUnit    string            `yaml:"target_unit,omitempty"`    // 目标显示单位
-	FormatType    string            `yaml:"format_type,omitempty"`    // 格式化类型：rate, bytes, number, time
+	ScaleFactor *float64 `yaml:"scale_factor,omitempty"` // 缩放因子，用于附加的数值调整
+	TargetUnit  string   `yaml:"target_unit,omitempty"`  // 目标显示单位
+	FormatType  string   `yaml:"format_type,omitempty"`  // 格式化类型：rate, bytes, number, time
+	Timeout     Duration `yaml:"timeout,omitempty"`      // 单个查询超时时间，覆盖 collection.query_timeout
+	Role        string   `yaml:"role,omitempty"`         // 指标角色，如 host.cpu_usage，用于填充主机资源概览
+	Datasource  string   `yaml:"datasource,omitempty"`   // 指标使用的数据源，覆盖分组的 datasource
+	// 多级阈值与区间阈值
+	WarningThreshold  *float64         `yaml:"warning_threshold,omitempty"`  // 警告阈值，未配置时按 threshold_type 推算
+	CriticalThreshold *float64         `yaml:"critical_threshold,omitempty"` // 严重阈值，未配置时使用 threshold
+	Range             *threshold.Range `yaml:"range,omitempty"`              // between/outside 使用的区间
+	// 按标签覆盖阈值，命中多条时取最具体的一条
+	ThresholdOverrides []threshold.Override `yaml:"threshold_overrides,omitempty"`
+	ThresholdQuery     string               `yaml:"threshold_query,omitempty"` // 返回逐序列阈值的 PromQL，优先于 threshold_overrides
+	// 趋势图
+	TrendQuery    string   `yaml:"trend_query,omitempty"`    // 范围查询，用于报告中的趋势折线图
+	TrendRange    Duration `yaml:"trend_range,omitempty"`    // 趋势时间范围，默认 24h
+	TrendStep     Duration `yaml:"trend_step,omitempty"`     // 采样间隔，默认按时间范围取约 120 个点，最小 1m
+	TrendLookback Duration `yaml:"trend_lookback,omitempty"` // 趋势窗口结束时间相对巡检时间的偏移，默认 0
+	// 健康看板中单条序列一天内的取值方式：min、max、avg 或 pNN（如 p95），默认按阈值方向选择
+	StatusAggregation string `yaml:"status_aggregation,omitempty"`
+	// query 返回范围向量时每条序列取值的方式：last（默认）、min、max 或 avg
+	MatrixAggregation string `yaml:"matrix_aggregation,omitempty"`
+	// 期望存在的序列，缺失时在报告中显示为异常行
+	Expect *ExpectConfig `yaml:"expect,omitempty"`
+	// 序列缺少配置的标签时的处理方式：drop（默认）、placeholder 或 flag
+	MissingLabelPolicy string `yaml:"missing_label_policy,omitempty"`
+}
+
+// 范围向量结果中每条序列的取值方式
+const (
+	MatrixAggregationLast = "last" // 最后一个采样点，默认
+	MatrixAggregationMin  = "min"
+	MatrixAggregationMax  = "max"
+	MatrixAggregationAvg  = "avg"
+)
+
+// 序列缺少配置的标签时的处理方式
+const (
+	MissingLabelDrop        = "drop"        // 丢弃该序列，默认
+	MissingLabelPlaceholder = "placeholder" // 保留该序列，缺失的标签显示为 "-"
+	MissingLabelFlag        = "flag"        // 保留该序列，并在报告中标出缺失的标签
+)
+
+// ThresholdRule 返回指标的阈值规则
+func (m MetricConfig) ThresholdRule() threshold.Rule {
+	return threshold.Rule{
+		Type:      m.ThresholdType,
+		Threshold: m.Threshold,
+		Warning:   m.WarningThreshold,
+		Critical:  m.CriticalThreshold,
+		Range:     m.Range,
+		Display:   m.Type == MetricTypeDisplay,
+	}
+}
+
+// ThresholdRules 返回指标的阈值规则集，包含按标签的覆盖
+func (m MetricConfig) ThresholdRules() (*threshold.RuleSet, error) {
+	return threshold.NewRuleSet(m.ThresholdRule(), m.ThresholdOverrides)
+}
+
+// AllDatasources 返回全部数据源，prometheus_url 作为名为 default 的数据源
+func (c *Config) AllDatasources() []prometheus.DatasourceConfig {
+	datasources := make([]prometheus.DatasourceConfig, 0, len(c.Datasources)+1)
+	if c.PrometheusURL != "" && !c.hasDatasource(prometheus.DefaultDatasource) {
+		datasources = append(datasources, prometheus.DatasourceConfig{
+			Name: prometheus.DefaultDatasource,
+			URL:  c.PrometheusURL,
+		})
+	}
+	return append(datasources, c.Datasources...)
+}
+
+// DefaultDatasource 返回未指定 datasource 的指标使用的数据源名称
+func (c *Config) DefaultDatasource() string {
+	datasources := c.AllDatasources()
+	if len(datasources) == 0 {
+		return prometheus.DefaultDatasource
+	}
+	return datasources[0].Name
+}
+
+// DatasourceFor 返回指标实际使用的数据源名称：指标 > 分组 > 默认
+func (c *Config) DatasourceFor(metricType MetricType, metric MetricConfig) string {
+	if metric.Datasource != "" {
+		return metric.Datasource
+	}
+	if metricType.Datasource != "" {
+		return metricType.Datasource
+	}
+	return c.DefaultDatasource()
+}
+
+func (c *Config) hasDatasource(name string) bool {
+	for _, ds := range c.Datasources {
+		if ds.Name == name {
+			return true
+		}
+	}
+	return false
 }
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"strings"
 	"testing"
+	"time"
 	"gopkg.in/yaml.v2"
 )
 
@@ -106,9 +108,7 @@ func TestConfigStructureValidation(t *testing.T) {
 						Threshold:     80.0,
 						ThresholdType: "greater",
 						Unit:          "%",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: Labels{{Name: "instance", Alias: "节点"}},
 					},
 					{
 						Name:        "CPU核心数",
@@ -117,9 +117,7 @@ func TestConfigStructureValidation(t *testing.T) {
 						Description: "CPU核心数",
 						Query:       "test_cpu_cores_query",
 						Unit:        "core",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: Labels{{Name: "instance", Alias: "节点"}},
 					},
 				},
 			},
@@ -161,4 +159,182 @@ func TestConfigStructureValidation(t *testing.T) {
 	if displayMetric.Type != "display" {
 		t.Error("展示类指标Type不正确")
 	}
-}
\ No newline at end of file
+}
+func TestMetricConfigLabelsOrder(t *testing.T) {
+	yamlData := `
+name: "磁盘使用率"
+labels:
+  mountpoint: "挂载点"
+  instance: "节点"
+  device: "设备"
+  fstype:
+`
+
+	var metric MetricConfig
+	if err := yaml.Unmarshal([]byte(yamlData), &metric); err != nil {
+		t.Fatalf("YAML解析失败: %v", err)
+	}
+
+	want := Labels{
+		{Name: "mountpoint", Alias: "挂载点"},
+		{Name: "instance", Alias: "节点"},
+		{Name: "device", Alias: "设备"},
+		{Name: "fstype", Alias: "fstype"}, // 未配置别名时使用标签名
+	}
+	if len(metric.Labels) != len(want) {
+		t.Fatalf("期望%d个标签，实际%d个", len(want), len(metric.Labels))
+	}
+	for i := range want {
+		if metric.Labels[i] != want[i] {
+			t.Errorf("第%d个标签应为%+v，实际%+v", i, want[i], metric.Labels[i])
+		}
+	}
+	if !metric.Labels.Has("device") || metric.Labels.Has("job") {
+		t.Error("Has 判断不正确")
+	}
+
+	// 序列化后保持顺序
+	out, err := yaml.Marshal(metric.Labels)
+	if err != nil {
+		t.Fatalf("YAML序列化失败: %v", err)
+	}
+	if string(out) != "mountpoint: 挂载点\ninstance: 节点\ndevice: 设备\nfstype: fstype\n" {
+		t.Errorf("序列化结果不正确:\n%s", out)
+	}
+
+	if err := yaml.Unmarshal([]byte("labels: [instance]"), &metric); err == nil {
+		t.Error("列表写法应该解析失败")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	var cfg Config
+	valid := `
+prometheus_url: "http://localhost:9090"
+cron_schedule: "0 9 * * *"
+metric_types:
+  - type: "基础资源使用情况"
+    metrics:
+      - name: "CPU使用率"
+        query: "cpu_usage"
+        threshold: 80
+        threshold_type: "greater"
+`
+	if err := yaml.Unmarshal([]byte(valid), &cfg); err != nil {
+		t.Fatalf("YAML解析失败: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("有效配置校验失败: %v", err)
+	}
+
+	invalid := `
+cron_schedule: "every day"
+metric_types:
+  - type: "基础资源使用情况"
+    metrics:
+      - name: "CPU使用率"
+        threshold_type: "bigger"
+        threshold_overrides:
+          - match: 'instance=~"("'
+            threshold: 90
+status:
+  timezone: "Mars/Olympus"
+`
+	cfg = Config{}
+	if err := yaml.Unmarshal([]byte(invalid), &cfg); err != nil {
+		t.Fatalf("YAML解析失败: %v", err)
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("无效配置应校验失败")
+	}
+	for _, want := range []string{"no Prometheus datasource", "query is empty", "unknown threshold_type", "cron_schedule", "Mars/Olympus"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误信息应包含 %q，实际: %v", want, err)
+		}
+	}
+}
+
+func TestValidationErrorsWithLines(t *testing.T) {
+	source := `prometheus_url: "http://localhost:9090"
+metric_types:
+  - type: "基础资源使用情况"
+    metrics:
+      - name: "CPU使用率"
+        query: "rate(cpu_seconds_total[5m]"
+        threshold_type: "grater"
+      - name: "CPU使用率"
+        query: "cpu_usage"
+        type: "monitor"
+notifications:
+  dingtalk:
+    enabled: true
+  email:
+    enabled: true
+    smtp_host: "smtp.example.com"
+    smtp_port: 465
+    from: "promai@example.com"
+`
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(source), &cfg); err != nil {
+		t.Fatalf("YAML解析失败: %v", err)
+	}
+
+	// 默认不解析 PromQL
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("无效配置应校验失败")
+	}
+	if strings.Contains(err.Error(), "invalid PromQL") {
+		t.Errorf("默认选项不应解析 PromQL: %v", err)
+	}
+
+	err = cfg.ValidateWith(ValidateOptions{ParseQueries: true})
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("应返回 ValidationErrors，实际: %T %v", err, err)
+	}
+	errs = errs.WithLines([]byte(source))
+
+	want := map[string]int{
+		"metric_types[0].metrics[0].threshold_type": 7,
+		"metric_types[0].metrics[0].query":          6,
+		"metric_types[0].metrics[1].name":           8,
+		"metric_types[0].metrics[1].type":           10,
+		"notifications.dingtalk.webhook":            12,
+		"notifications.email.to":                    14,
+	}
+	got := make(map[string]int, len(errs))
+	for _, e := range errs {
+		got[e.Path] = e.Line
+	}
+	for path, line := range want {
+		if got[path] != line {
+			t.Errorf("%s 行号期望 %d，实际 %d（全部错误: %v）", path, line, got[path], errs)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("错误数量期望 %d，实际 %d: %v", len(want), len(got), errs)
+	}
+	if !strings.HasPrefix(errs.Error(), "line ") {
+		t.Errorf("错误信息应以行号开头: %v", errs)
+	}
+}
+
+func TestPDFConfigTimeout(t *testing.T) {
+	cases := map[string]time.Duration{
+		"timeout: 60":    60 * time.Second,
+		`timeout: "90s"`: 90 * time.Second,
+		`timeout: "2m"`:  2 * time.Minute,
+		"enabled: true":  0,
+	}
+	for source, want := range cases {
+		var cfg PDFConfig
+		if err := yaml.Unmarshal([]byte(source), &cfg); err != nil {
+			t.Fatalf("解析 %q 失败: %v", source, err)
+		}
+		if got := cfg.ReportConfig().Timeout; got != want {
+			t.Errorf("%q 解析结果期望 %v，实际 %v", source, want, got)
+		}
+	}
+}
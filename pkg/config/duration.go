@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration 支持 "30s"、"5m" 这类写法的时长配置，纯数字按秒处理
+type Duration time.Duration
+
+// UnmarshalYAML 解析时长配置
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var seconds int64
+	if err := unmarshal(&seconds); err == nil {
+		*d = Duration(time.Duration(seconds) * time.Second)
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML 输出为 "30s" 格式
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Std 转换为 time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String 返回可读格式
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
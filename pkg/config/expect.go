@@ -0,0 +1,30 @@
+package config
+
+import "PromAI/pkg/threshold"
+
+// 期望序列检查的默认配置
+const (
+	defaultExpectLabel    = "instance"
+	defaultAbsentSeverity = threshold.StatusCritical
+)
+
+// ExpectConfig 指标查询结果中期望存在的序列。缺失的序列在报告中显示为 absent_severity 状态的行，
+// 避免目标消失时报告只显示"未查询到数据"
+type ExpectConfig struct {
+	MinSeries         int      `yaml:"min_series,omitempty"`         // 查询结果至少包含的序列数
+	Label             string   `yaml:"label,omitempty"`              // 按该标签的值检查期望的序列，默认 instance
+	ExpectedInstances []string `yaml:"expected_instances,omitempty"` // 期望存在的标签值
+	InstancesQuery    string   `yaml:"instances_query,omitempty"`    // 返回期望序列的 PromQL，如 up{job="node"}，取结果中 label 标签的值
+	AbsentSeverity    string   `yaml:"absent_severity,omitempty"`    // 缺失序列的状态：warning 或 critical，默认 critical
+}
+
+// WithDefaults 返回填充默认值后的配置
+func (c ExpectConfig) WithDefaults() ExpectConfig {
+	if c.Label == "" {
+		c.Label = defaultExpectLabel
+	}
+	if c.AbsentSeverity == "" {
+		c.AbsentSeverity = defaultAbsentSeverity
+	}
+	return c
+}
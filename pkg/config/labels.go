@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Label 指标展示的标签及其别名（报告中的列名）
+type Label struct {
+	Name  string
+	Alias string
+}
+
+// Labels 按配置文件中的书写顺序保存的标签，报告表格按此顺序输出列。
+// 沿用 map 写法：
+//
+//	labels:
+//	  instance: "节点"
+//	  mountpoint: "挂载点"
+type Labels []Label
+
+// UnmarshalYAML 按书写顺序解析标签，别名为空时使用标签名
+func (l *Labels) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return fmt.Errorf("labels must be a mapping of label name to alias: %w", err)
+	}
+
+	labels := make(Labels, 0, len(items))
+	for _, item := range items {
+		name := fmt.Sprint(item.Key)
+		alias := name
+		if item.Value != nil {
+			if s := fmt.Sprint(item.Value); s != "" {
+				alias = s
+			}
+		}
+		labels = append(labels, Label{Name: name, Alias: alias})
+	}
+	*l = labels
+	return nil
+}
+
+// MarshalYAML 输出为保持顺序的 map 写法
+func (l Labels) MarshalYAML() (interface{}, error) {
+	items := make(yaml.MapSlice, 0, len(l))
+	for _, label := range l {
+		items = append(items, yaml.MapItem{Key: label.Name, Value: label.Alias})
+	}
+	return items, nil
+}
+
+// Has 判断是否配置了指定标签
+func (l Labels) Has(name string) bool {
+	for _, label := range l {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
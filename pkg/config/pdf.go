@@ -0,0 +1,19 @@
+package config
+
+import "PromAI/pkg/report"
+
+// PDFConfig PDF 导出配置。timeout 按 Duration 解析，支持 "60s" 写法，纯数字按秒处理
+type PDFConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	ChromePath string   `yaml:"chrome_path"` // Chromium 路径，默认读取 CHROMEDP_CHROME_PATH 环境变量
+	Timeout    Duration `yaml:"timeout"`     // 渲染超时时间，默认 60s
+}
+
+// ReportConfig 转换为报告导出使用的配置
+func (c PDFConfig) ReportConfig() report.PDFConfig {
+	return report.PDFConfig{
+		Enabled:    c.Enabled,
+		ChromePath: c.ChromePath,
+		Timeout:    c.Timeout.Std(),
+	}
+}
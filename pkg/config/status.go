@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 健康看板中单条序列在一天内的判定方式
+const (
+	SeriesModeWorst     = "worst"     // 任一采样点异常即判为异常，取最严重的状态
+	SeriesModeViolation = "violation" // 超出阈值的时间占比达到 violation_percent 才判为异常
+)
+
+// 健康看板默认配置
+const (
+	defaultStatusDays       = 7
+	defaultStatusStep       = time.Hour
+	defaultViolationPercent = 10
+	defaultRefreshInterval  = 5 * time.Minute
+	defaultStatusCacheDir   = "status_cache"
+)
+
+// StatusConfig 服务健康看板配置
+type StatusConfig struct {
+	Days             int      `yaml:"days"`              // 显示最近几天，默认 7
+	Timezone         string   `yaml:"timezone"`          // 划分自然日使用的时区，如 Asia/Shanghai，默认本地时区
+	Step             Duration `yaml:"step"`              // 范围查询的采样间隔，默认 1h
+	SeriesMode       string   `yaml:"series_mode"`       // 单条序列的判定方式：worst（默认）或 violation
+	ViolationPercent float64  `yaml:"violation_percent"` // violation 模式下超出阈值的时间占比（百分比），默认 10
+	RefreshInterval  Duration `yaml:"refresh_interval"`  // 后台刷新当天状态的间隔，默认 5m
+	CacheDir         string   `yaml:"cache_dir"`         // 已结束自然日状态的缓存目录，默认 status_cache
+}
+
+// WithDefaults 返回填充默认值后的配置
+func (c StatusConfig) WithDefaults() StatusConfig {
+	if c.Days <= 0 {
+		c.Days = defaultStatusDays
+	}
+	if c.Step <= 0 {
+		c.Step = Duration(defaultStatusStep)
+	}
+	if c.SeriesMode == "" {
+		c.SeriesMode = SeriesModeWorst
+	}
+	if c.ViolationPercent <= 0 {
+		c.ViolationPercent = defaultViolationPercent
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = Duration(defaultRefreshInterval)
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = defaultStatusCacheDir
+	}
+	return c
+}
+
+// Location 返回划分自然日使用的时区，未配置时为本地时区
+func (c StatusConfig) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status timezone %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// ValidateStatusAggregation 校验 status_aggregation 的取值：min、max、avg 或 pNN（如 p95、p99.9）
+func ValidateStatusAggregation(aggregation string) error {
+	switch strings.ToLower(aggregation) {
+	case "min", "max", "avg":
+		return nil
+	}
+	if _, err := ParsePercentile(aggregation); err != nil {
+		return fmt.Errorf("status_aggregation must be min, max, avg or pNN, got %q", aggregation)
+	}
+	return nil
+}
+
+// ParsePercentile 解析 pNN 形式的百分位，返回 0-100 之间的值
+func ParsePercentile(aggregation string) (float64, error) {
+	aggregation = strings.ToLower(aggregation)
+	if !strings.HasPrefix(aggregation, "p") {
+		return 0, fmt.Errorf("not a percentile: %q", aggregation)
+	}
+	q, err := strconv.ParseFloat(aggregation[1:], 64)
+	if err != nil || q < 0 || q > 100 {
+		return 0, fmt.Errorf("invalid percentile: %q", aggregation)
+	}
+	return q, nil
+}
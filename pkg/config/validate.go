@@ -0,0 +1,312 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"PromAI/pkg/report"
+	"PromAI/pkg/threshold"
+	"PromAI/pkg/units"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/robfig/cron/v3"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// 指标类型
+const (
+	MetricTypeMonitoring = "monitoring" // 按阈值区分颜色告警，默认
+	MetricTypeDisplay    = "display"    // 仅展示数据
+)
+
+// ValidateOptions 配置校验选项
+type ValidateOptions struct {
+	ParseQueries bool // 使用 Prometheus 解析器检查每个 PromQL 表达式
+}
+
+// ValidationError 一条配置校验错误
+type ValidationError struct {
+	Path    string // 出错字段的路径，如 metric_types[0].metrics[2].threshold_type
+	Line    int    // 在配置文件中的行号，未知时为 0
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors 校验发现的全部错误
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// add 记录一条错误
+func (errs *ValidationErrors) add(path, format string, args ...interface{}) {
+	*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate 使用默认选项校验配置
+func (c *Config) Validate() error {
+	return c.ValidateWith(ValidateOptions{})
+}
+
+// ValidateWith 检查配置能否正常使用，返回 ValidationErrors。重新加载配置时校验失败会继续使用旧配置
+func (c *Config) ValidateWith(opts ValidateOptions) error {
+	var errs ValidationErrors
+
+	datasources := c.AllDatasources()
+	if len(datasources) == 0 {
+		errs.add("prometheus_url", "no Prometheus datasource configured: set prometheus_url or datasources")
+	}
+	names := make(map[string]bool, len(datasources))
+	for i, ds := range c.Datasources {
+		path := fmt.Sprintf("datasources[%d]", i)
+		if ds.Name == "" {
+			errs.add(path+".name", "datasource name is empty")
+		} else if names[ds.Name] {
+			errs.add(path+".name", "duplicate datasource %q", ds.Name)
+		}
+		if ds.URL == "" {
+			errs.add(path+".url", "datasource %q: url is empty", ds.Name)
+		}
+		names[ds.Name] = true
+	}
+	if c.PrometheusURL != "" {
+		names[c.DefaultDatasource()] = true
+	}
+
+	types := make(map[string]bool, len(c.MetricTypes))
+	metricNames := make(map[string]string)
+	for i, metricType := range c.MetricTypes {
+		typePath := fmt.Sprintf("metric_types[%d]", i)
+		if types[metricType.Type] {
+			errs.add(typePath+".type", "duplicate metric type %q", metricType.Type)
+		}
+		types[metricType.Type] = true
+		if metricType.Datasource != "" && !names[metricType.Datasource] {
+			errs.add(typePath+".datasource", "unknown datasource %q", metricType.Datasource)
+		}
+
+		for j, metric := range metricType.Metrics {
+			path := fmt.Sprintf("%s.metrics[%d]", typePath, j)
+			c.validateMetric(&errs, path, metric, names, opts)
+			if metric.Name == "" {
+				continue
+			}
+			// 健康看板和指标目录按名称区分指标
+			if first, exists := metricNames[metric.Name]; exists {
+				errs.add(path+".name", "duplicate metric name %q, first defined at %s", metric.Name, first)
+			} else {
+				metricNames[metric.Name] = path
+			}
+		}
+	}
+
+	if c.CronSchedule != "" {
+		if _, err := cron.ParseStandard(c.CronSchedule); err != nil {
+			errs.add("cron_schedule", "invalid cron_schedule %q: %v", c.CronSchedule, err)
+		}
+	}
+	if c.ReportCleanup.CronSchedule != "" {
+		if _, err := cron.ParseStandard(c.ReportCleanup.CronSchedule); err != nil {
+			errs.add("report_cleanup.cron_schedule", "invalid cron_schedule %q: %v", c.ReportCleanup.CronSchedule, err)
+		}
+	}
+	if c.ReportCleanup.Enabled && c.ReportCleanup.MaxAge <= 0 {
+		errs.add("report_cleanup.max_age", "max_age must be greater than 0 when report_cleanup is enabled")
+	}
+
+	if _, err := c.Status.Location(); err != nil {
+		errs.add("status.timezone", "%v", err)
+	}
+	switch c.Status.SeriesMode {
+	case "", SeriesModeWorst, SeriesModeViolation:
+	default:
+		errs.add("status.series_mode", "series_mode must be %q or %q, got %q", SeriesModeWorst, SeriesModeViolation, c.Status.SeriesMode)
+	}
+
+	c.validateNotifications(&errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateMetric 校验单个指标
+func (c *Config) validateMetric(errs *ValidationErrors, path string, metric MetricConfig, datasources map[string]bool, opts ValidateOptions) {
+	if metric.Name == "" {
+		errs.add(path+".name", "metric name is empty")
+	}
+	if metric.Query == "" {
+		errs.add(path+".query", "metric %q: query is empty", metric.Name)
+	}
+	switch metric.Type {
+	case "", MetricTypeMonitoring, MetricTypeDisplay:
+	default:
+		errs.add(path+".type", "unknown metric type %q, must be %q or %q", metric.Type, MetricTypeMonitoring, MetricTypeDisplay)
+	}
+	if metric.ThresholdType != "" && !threshold.Valid(metric.ThresholdType) {
+		errs.add(path+".threshold_type", "unknown threshold_type %q", metric.ThresholdType)
+	}
+	if metric.ThresholdType == threshold.Between || metric.ThresholdType == threshold.Outside {
+		if metric.Range == nil {
+			errs.add(path+".range", "threshold_type %q requires range", metric.ThresholdType)
+		} else if metric.Range.Min > metric.Range.Max {
+			errs.add(path+".range", "range min %v is greater than max %v", metric.Range.Min, metric.Range.Max)
+		}
+	}
+	for k, override := range metric.ThresholdOverrides {
+		if _, err := threshold.ParseSelector(override.Match); err != nil {
+			errs.add(fmt.Sprintf("%s.threshold_overrides[%d].match", path, k), "%v", err)
+		}
+	}
+
+	switch metric.FormatType {
+	case "", units.FormatRate, units.FormatBytes, units.FormatNumber, units.FormatTime, units.FormatPercent:
+	default:
+		errs.add(path+".format_type", "unknown format_type %q", metric.FormatType)
+	}
+	if metric.TargetUnit != "" && metric.TargetUnit != "auto" {
+		if _, err := units.Convert(1, metric.Unit, metric.TargetUnit); err != nil {
+			errs.add(path+".target_unit", "cannot convert unit %q to target_unit %q: %v", metric.Unit, metric.TargetUnit, err)
+		}
+	}
+	if report.IsHostRole(metric.Role) && !report.KnownHostRole(metric.Role) {
+		errs.add(path+".role", "unknown host role %q", metric.Role)
+	}
+	if metric.Datasource != "" && !datasources[metric.Datasource] {
+		errs.add(path+".datasource", "unknown datasource %q", metric.Datasource)
+	}
+	if metric.StatusAggregation != "" {
+		if err := ValidateStatusAggregation(metric.StatusAggregation); err != nil {
+			errs.add(path+".status_aggregation", "%v", err)
+		}
+	}
+
+	switch metric.MatrixAggregation {
+	case "", MatrixAggregationLast, MatrixAggregationMin, MatrixAggregationMax, MatrixAggregationAvg:
+	default:
+		errs.add(path+".matrix_aggregation", "matrix_aggregation must be one of last, min, max or avg, got %q", metric.MatrixAggregation)
+	}
+
+	switch metric.MissingLabelPolicy {
+	case "", MissingLabelDrop, MissingLabelPlaceholder, MissingLabelFlag:
+	default:
+		errs.add(path+".missing_label_policy", "missing_label_policy must be one of drop, placeholder or flag, got %q", metric.MissingLabelPolicy)
+	}
+	if metric.Expect != nil {
+		if metric.Expect.MinSeries < 0 {
+			errs.add(path+".expect.min_series", "min_series must not be negative, got %d", metric.Expect.MinSeries)
+		}
+		switch metric.Expect.AbsentSeverity {
+		case "", threshold.StatusWarning, threshold.StatusCritical:
+		default:
+			errs.add(path+".expect.absent_severity", "absent_severity must be %q or %q, got %q", threshold.StatusWarning, threshold.StatusCritical, metric.Expect.AbsentSeverity)
+		}
+	}
+
+	if opts.ParseQueries {
+		queries := []struct{ field, query string }{
+			{"query", metric.Query},
+			{"threshold_query", metric.ThresholdQuery},
+			{"trend_query", metric.TrendQuery},
+		}
+		if metric.Expect != nil {
+			queries = append(queries, struct{ field, query string }{"expect.instances_query", metric.Expect.InstancesQuery})
+		}
+		for _, q := range queries {
+			if q.query == "" {
+				continue
+			}
+			if _, err := parser.ParseExpr(q.query); err != nil {
+				errs.add(path+"."+q.field, "invalid PromQL: %v", err)
+			}
+		}
+	}
+}
+
+// validateNotifications 检查已启用的通知方式是否配置完整
+func (c *Config) validateNotifications(errs *ValidationErrors) {
+	n := c.Notifications
+	if n.Dingtalk.Enabled && n.Dingtalk.Webhook == "" {
+		errs.add("notifications.dingtalk.webhook", "webhook is required when dingtalk is enabled")
+	}
+	if n.Wecom.Enabled && n.Wecom.Webhook == "" {
+		errs.add("notifications.wecom.webhook", "webhook is required when wecom is enabled")
+	}
+	if n.Email.Enabled {
+		if n.Email.SMTPHost == "" {
+			errs.add("notifications.email.smtp_host", "smtp_host is required when email is enabled")
+		}
+		if n.Email.SMTPPort <= 0 {
+			errs.add("notifications.email.smtp_port", "smtp_port is required when email is enabled")
+		}
+		if n.Email.From == "" {
+			errs.add("notifications.email.from", "from is required when email is enabled")
+		}
+		if len(n.Email.To) == 0 {
+			errs.add("notifications.email.to", "at least one recipient is required when email is enabled")
+		}
+	}
+}
+
+// pathSegment 匹配路径中的一段，如 metrics[2]
+var pathSegment = regexp.MustCompile(`^([^\[]+)(?:\[(\d+)\])?$`)
+
+// WithLines 按配置文件内容为每条错误补充行号，找不到字段时使用最近的上级字段所在行
+func (errs ValidationErrors) WithLines(source []byte) ValidationErrors {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(source, &root); err != nil || len(root.Content) == 0 {
+		return errs
+	}
+	result := make(ValidationErrors, len(errs))
+	for i, err := range errs {
+		err.Line = lineOf(root.Content[0], err.Path)
+		result[i] = err
+	}
+	return result
+}
+
+// lineOf 返回路径在 YAML 节点树中对应的行号
+func lineOf(node *yamlv3.Node, path string) int {
+	line := 0
+	for _, segment := range strings.Split(path, ".") {
+		match := pathSegment.FindStringSubmatch(segment)
+		if match == nil || node.Kind != yamlv3.MappingNode {
+			return line
+		}
+		var value *yamlv3.Node
+		for k := 0; k+1 < len(node.Content); k += 2 {
+			if node.Content[k].Value == match[1] {
+				line = node.Content[k].Line
+				value = node.Content[k+1]
+				break
+			}
+		}
+		if value == nil {
+			return line
+		}
+		node = value
+		if match[2] != "" {
+			index, _ := strconv.Atoi(match[2])
+			if node.Kind != yamlv3.SequenceNode || index >= len(node.Content) {
+				return line
+			}
+			node = node.Content[index]
+			line = node.Line
+		}
+	}
+	return line
+}
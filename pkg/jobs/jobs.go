@@ -0,0 +1,225 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"PromAI/pkg/metrics"
+	"PromAI/pkg/report"
+)
+
+// 任务状态
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// maxJobs 保留的任务记录数量
+const maxJobs = 100
+
+// RunFunc 执行一次完整巡检（收集指标并生成报告），返回报告文件路径
+type RunFunc func(ctx context.Context, progress metrics.ProgressFunc) (string, error)
+
+// Job 一次巡检任务的状态
+type Job struct {
+	ID              string           `json:"id"`
+	Source          string           `json:"source"` // 触发来源：web、api、cron
+	Status          string           `json:"status"`
+	CreatedAt       time.Time        `json:"created_at"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	FinishedAt      *time.Time       `json:"finished_at,omitempty"`
+	Progress        metrics.Progress `json:"progress"`
+	CompletedGroups []string         `json:"completed_groups"`
+	ReportID        string           `json:"report_id,omitempty"`
+	ReportFile      string           `json:"report_file,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+// Finished 任务是否已结束
+func (j Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Manager 巡检任务管理器：同一时间只执行一次巡检，执行期间的新请求合并到当前任务
+type Manager struct {
+	run RunFunc
+
+	mu          sync.Mutex
+	jobs        map[string]*Job
+	order       []string
+	active      *Job
+	callbacks   map[string][]func(Job)
+	subscribers map[string][]chan Job
+}
+
+// NewManager 创建任务管理器
+func NewManager(run RunFunc) *Manager {
+	return &Manager{
+		run:         run,
+		jobs:        make(map[string]*Job),
+		callbacks:   make(map[string][]func(Job)),
+		subscribers: make(map[string][]chan Job),
+	}
+}
+
+// Submit 提交巡检任务。已有任务在排队或执行时不会重复收集，直接返回该任务；
+// onDone 在任务结束后调用，可为 nil。第二个返回值表示是否新建了任务。
+func (m *Manager) Submit(source string, onDone func(Job)) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job := m.active; job != nil {
+		if onDone != nil {
+			m.callbacks[job.ID] = append(m.callbacks[job.ID], onDone)
+		}
+		log.Printf("巡检任务 %s 正在执行，%s 请求合并到该任务", job.ID, source)
+		return m.copyJob(job), false
+	}
+
+	job := &Job{
+		ID:              newJobID(),
+		Source:          source,
+		Status:          StatusPending,
+		CreatedAt:       time.Now(),
+		CompletedGroups: []string{},
+	}
+	m.jobs[job.ID] = job
+	m.order = append(m.order, job.ID)
+	// 超出数量时丢弃最早的记录
+	for len(m.order) > maxJobs {
+		delete(m.jobs, m.order[0])
+		m.order = m.order[1:]
+	}
+	m.active = job
+	if onDone != nil {
+		m.callbacks[job.ID] = append(m.callbacks[job.ID], onDone)
+	}
+
+	go m.execute(job)
+	return m.copyJob(job), true
+}
+
+// Get 返回任务状态
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	return m.copyJob(job), true
+}
+
+// Subscribe 订阅任务状态变化，任务结束后通道关闭；调用返回的函数取消订阅
+func (m *Manager) Subscribe(id string) (<-chan Job, func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, nil, false
+	}
+
+	ch := make(chan Job, 16)
+	ch <- m.copyJob(job)
+	if job.Finished() {
+		close(ch)
+		return ch, func() {}, true
+	}
+	m.subscribers[id] = append(m.subscribers[id], ch)
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		subs := m.subscribers[id]
+		for i, sub := range subs {
+			if sub == ch {
+				m.subscribers[id] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, cancel, true
+}
+
+func (m *Manager) execute(job *Job) {
+	m.update(job, func(j *Job) {
+		now := time.Now()
+		j.Status = StatusRunning
+		j.StartedAt = &now
+	})
+	log.Printf("巡检任务 %s 开始执行（来源: %s）", job.ID, job.Source)
+
+	reportFile, err := m.run(context.Background(), func(p metrics.Progress) {
+		m.update(job, func(j *Job) {
+			j.Progress = p
+			j.CompletedGroups = append(j.CompletedGroups, p.Group)
+		})
+	})
+
+	m.mu.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		log.Printf("巡检任务 %s 失败: %v", job.ID, err)
+	} else {
+		job.Status = StatusSucceeded
+		job.ReportFile = reportFile
+		job.ReportID = report.IDFromFilename(reportFile)
+		log.Printf("巡检任务 %s 完成: %s", job.ID, reportFile)
+	}
+	m.active = nil
+	final := m.copyJob(job)
+	m.publish(job.ID, final)
+	for _, ch := range m.subscribers[job.ID] {
+		close(ch)
+	}
+	delete(m.subscribers, job.ID)
+	callbacks := m.callbacks[job.ID]
+	delete(m.callbacks, job.ID)
+	m.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(final)
+	}
+}
+
+// update 修改任务状态并通知订阅者
+func (m *Manager) update(job *Job, fn func(*Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(job)
+	m.publish(job.ID, m.copyJob(job))
+}
+
+// publish 向订阅者发送任务状态，订阅者处理不及时时丢弃中间状态
+func (m *Manager) publish(id string, job Job) {
+	for _, ch := range m.subscribers[id] {
+		select {
+		case ch <- job:
+		default:
+		}
+	}
+}
+
+func (m *Manager) copyJob(job *Job) Job {
+	c := *job
+	c.CompletedGroups = append([]string{}, job.CompletedGroups...)
+	return c
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"PromAI/pkg/metrics"
+)
+
+func waitDone(t *testing.T, done <-chan Job) Job {
+	t.Helper()
+	select {
+	case job := <-done:
+		return job
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待任务结束超时")
+		return Job{}
+	}
+}
+
+func TestManagerDeduplicatesActiveRuns(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	manager := NewManager(func(ctx context.Context, progress metrics.ProgressFunc) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		progress(metrics.Progress{Group: "基础资源使用情况", GroupsDone: 1, GroupsTotal: 1})
+		return "reports/inspection_report_20240101_080000.html", nil
+	})
+
+	done := make(chan Job, 2)
+	first, created := manager.Submit("web", func(job Job) { done <- job })
+	if !created {
+		t.Fatal("第一次提交应创建任务")
+	}
+	second, created := manager.Submit("cron", func(job Job) { done <- job })
+	if created || second.ID != first.ID {
+		t.Errorf("执行期间的提交应合并到当前任务: %s != %s", second.ID, first.ID)
+	}
+
+	updates, cancel, ok := manager.Subscribe(first.ID)
+	if !ok {
+		t.Fatal("订阅任务失败")
+	}
+	defer cancel()
+
+	close(release)
+	for _, job := range []Job{waitDone(t, done), waitDone(t, done)} {
+		if job.Status != StatusSucceeded || job.ReportID != "20240101_080000" {
+			t.Errorf("任务结果不正确: %+v", job)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("合并后只应执行一次巡检，实际 %d 次", n)
+	}
+
+	// 订阅通道在任务结束后关闭
+	var last Job
+	for job := range updates {
+		last = job
+	}
+	if len(last.CompletedGroups) != 1 {
+		t.Errorf("订阅应收到指标组完成进度: %+v", last)
+	}
+
+	// 上一个任务结束后再提交会新建任务
+	third, created := manager.Submit("api", func(job Job) { done <- job })
+	if !created || third.ID == first.ID {
+		t.Error("任务结束后的提交应创建新任务")
+	}
+	waitDone(t, done)
+}
+
+func TestManagerRecordsFailure(t *testing.T) {
+	manager := NewManager(func(ctx context.Context, progress metrics.ProgressFunc) (string, error) {
+		return "", errors.New("prometheus unavailable")
+	})
+
+	done := make(chan Job, 1)
+	job, _ := manager.Submit("api", func(job Job) { done <- job })
+	result := waitDone(t, done)
+	if result.Status != StatusFailed || result.Error != "prometheus unavailable" {
+		t.Errorf("失败任务状态不正确: %+v", result)
+	}
+
+	stored, ok := manager.Get(job.ID)
+	if !ok || !stored.Finished() {
+		t.Errorf("应能查询到已结束的任务: %+v", stored)
+	}
+}
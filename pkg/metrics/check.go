@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+)
+
+// 查询检查结果状态
+const (
+	CheckOK      = "ok"      // 查询正常
+	CheckWarning = "warning" // 查询成功，但结果可能不符合预期
+	CheckFailed  = "failed"  // 查询失败或结果无法生成报告
+)
+
+// maxCheckSamples 每个指标展示的样例数
+const maxCheckSamples = 3
+
+// MissingLabel 配置的标签在多少条序列上缺失或为空
+type MissingLabel struct {
+	Name   string `json:"name"`
+	Series int    `json:"series"`
+}
+
+// QueryCheck 单个指标查询的检查结果
+type QueryCheck struct {
+	Group         string         `json:"group"`
+	Metric        string         `json:"metric"`
+	Datasource    string         `json:"datasource"`
+	Query         string         `json:"query"`
+	Status        string         `json:"status"`
+	ResultType    string         `json:"result_type,omitempty"`
+	SeriesCount   int            `json:"series_count"`
+	MissingLabels []MissingLabel `json:"missing_labels,omitempty"`
+	Samples       []string       `json:"samples,omitempty"`
+	Latency       time.Duration  `json:"latency"`
+	Warnings      []string       `json:"warnings,omitempty"` // Prometheus 返回的警告
+	Problems      []string       `json:"problems,omitempty"` // 检查发现的问题，决定 Status
+	Error         string         `json:"error,omitempty"`
+}
+
+// Failed 查询是否无法正常生成报告
+func (q QueryCheck) Failed() bool {
+	return q.Status == CheckFailed
+}
+
+// CheckQueries 对每个指标的 query 执行一次即时查询并检查结果，不生成报告，结果按配置顺序返回
+func (c *Collector) CheckQueries(ctx context.Context) []QueryCheck {
+	ctx, cancel := context.WithTimeout(ctx, c.totalTimeout())
+	defer cancel()
+
+	var jobs []metricJob
+	for i, metricType := range c.config.MetricTypes {
+		for j, metric := range metricType.Metrics {
+			jobs = append(jobs, metricJob{groupIndex: i, metricIndex: j, metricType: metricType, metric: metric})
+		}
+	}
+
+	checks := make([]QueryCheck, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				checks[i] = c.checkQuery(ctx, jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return checks
+}
+
+// checkQuery 执行单个指标的查询并检查结果
+func (c *Collector) checkQuery(ctx context.Context, job metricJob) QueryCheck {
+	metric := job.metric
+	check := QueryCheck{
+		Group:      job.metricType.Type,
+		Metric:     metric.Name,
+		Datasource: c.config.DatasourceFor(job.metricType, metric),
+		Query:      metric.Query,
+	}
+	fail := func(err error) QueryCheck {
+		check.Status = CheckFailed
+		check.Error = err.Error()
+		return check
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(fmt.Errorf("检查超时，未执行查询: %w", err))
+	}
+	_, client, err := c.ClientFor(job.metricType, metric)
+	if err != nil {
+		return fail(err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, c.queryTimeout(metric))
+	defer cancel()
+
+	start := time.Now()
+	result, warnings, err := client.Query(queryCtx, metric.Query, start)
+	check.Latency = time.Since(start)
+	check.Warnings = warnings
+	if err != nil {
+		if errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+			return fail(fmt.Errorf("查询超时: %w", err))
+		}
+		return fail(err)
+	}
+
+	inspectResult(&check, metric, result)
+	return check
+}
+
+// inspectResult 统计查询结果的类型、序列数、缺失的标签和样例，并据此确定检查状态
+func inspectResult(check *QueryCheck, metric config.MetricConfig, result model.Value) {
+	var series []model.Metric
+	switch v := result.(type) {
+	case model.Vector:
+		check.ResultType = v.Type().String()
+		for _, sample := range v {
+			series = append(series, sample.Metric)
+			if len(check.Samples) < maxCheckSamples {
+				check.Samples = append(check.Samples, fmt.Sprintf("%s => %s", sample.Metric, sample.Value))
+			}
+		}
+	case model.Matrix:
+		check.ResultType = v.Type().String()
+		for _, stream := range v {
+			series = append(series, stream.Metric)
+			if len(check.Samples) < maxCheckSamples && len(stream.Values) > 0 {
+				last := stream.Values[len(stream.Values)-1]
+				check.Samples = append(check.Samples, fmt.Sprintf("%s => %s（%d 个采样点）", stream.Metric, last.Value, len(stream.Values)))
+			}
+		}
+	case *model.Scalar:
+		// 标量和字符串结果在报告中显示为一行不带标签的数据，不检查配置的标签
+		check.ResultType = v.Type().String()
+		check.SeriesCount = 1
+		check.Samples = []string{v.Value.String()}
+	case *model.String:
+		check.ResultType = v.Type().String()
+		check.SeriesCount = 1
+		check.Samples = []string{v.Value}
+	case nil:
+		check.Problems = append(check.Problems, "查询没有返回结果")
+	default:
+		check.ResultType = v.Type().String()
+		check.Problems = append(check.Problems, fmt.Sprintf("不支持的结果类型 %s，报告中不会显示该指标", check.ResultType))
+	}
+	if series != nil {
+		check.SeriesCount = len(series)
+	}
+
+	status := CheckOK
+	if len(check.Problems) > 0 {
+		status = CheckFailed
+	}
+	if len(check.Warnings) > 0 && status == CheckOK {
+		status = CheckWarning
+	}
+	if check.ResultType != "" && check.SeriesCount == 0 {
+		check.Problems = append(check.Problems, "查询结果为空")
+		if status == CheckOK {
+			status = CheckWarning
+		}
+	}
+
+	for _, label := range metric.Labels {
+		missing := 0
+		for _, m := range series {
+			if m[model.LabelName(label.Name)] == "" {
+				missing++
+			}
+		}
+		if missing == 0 {
+			continue
+		}
+		check.MissingLabels = append(check.MissingLabels, MissingLabel{Name: label.Name, Series: missing})
+		// 按 drop 处理时缺少标签的序列不会出现在报告中
+		if missing == len(series) && missingLabelPolicy(metric) == config.MissingLabelDrop {
+			check.Problems = append(check.Problems, fmt.Sprintf("所有序列都缺少标签 %s", label.Name))
+			status = CheckFailed
+		} else {
+			check.Problems = append(check.Problems, fmt.Sprintf("%d/%d 条序列缺少标签 %s", missing, len(series), label.Name))
+			if status == CheckOK {
+				status = CheckWarning
+			}
+		}
+	}
+	check.Status = status
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+)
+
+func TestCheckQueries(t *testing.T) {
+	labels := config.Labels{{Name: "instance", Alias: "节点"}, {Name: "mountpoint", Alias: "挂载点"}}
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					{Name: "ok", Query: "q_ok", Labels: labels},
+					{Name: "partial", Query: "q_partial", Labels: labels},
+					{Name: "typo", Query: "q_typo", Labels: labels},
+					{Name: "empty", Query: "q_empty", Labels: labels},
+					{Name: "warned", Query: "q_warned", Labels: labels},
+					{Name: "broken", Query: "q_broken", Labels: labels},
+					{Name: "matrix", Query: "q_matrix", Labels: labels},
+					{Name: "scalar", Query: "q_scalar", Labels: labels},
+				},
+			},
+		},
+	}
+
+	api := &MockPrometheusAPI{
+		responses: map[string]model.Value{
+			"q_ok": model.Vector{
+				&model.Sample{Metric: model.Metric{"instance": "a", "mountpoint": "/"}, Value: 1},
+			},
+			"q_partial": model.Vector{
+				&model.Sample{Metric: model.Metric{"instance": "a", "mountpoint": "/"}, Value: 1},
+				&model.Sample{Metric: model.Metric{"instance": "b"}, Value: 2},
+			},
+			"q_typo": model.Vector{
+				&model.Sample{Metric: model.Metric{"instance": "a"}, Value: 1},
+			},
+			"q_empty": model.Vector{},
+			"q_warned": model.Vector{
+				&model.Sample{Metric: model.Metric{"instance": "a", "mountpoint": "/"}, Value: 1},
+			},
+			"q_matrix": model.Matrix{
+				&model.SampleStream{Metric: model.Metric{"instance": "a", "mountpoint": "/"}, Values: []model.SamplePair{{Value: 1}, {Value: 3}}},
+			},
+			"q_scalar": &model.Scalar{Value: 7},
+		},
+		warnings: map[string]v1.Warnings{"q_warned": {"partial response"}},
+		errs:     map[string]error{"q_broken": errors.New("parse error")},
+	}
+
+	checks := NewCollector(api, testConfig).CheckQueries(context.Background())
+	if len(checks) != 8 {
+		t.Fatalf("期望 8 条检查结果，实际 %d", len(checks))
+	}
+	byName := make(map[string]QueryCheck, len(checks))
+	for i, check := range checks {
+		if check.Metric != testConfig.MetricTypes[0].Metrics[i].Name {
+			t.Errorf("检查结果应按配置顺序返回，第 %d 条为 %s", i, check.Metric)
+		}
+		byName[check.Metric] = check
+	}
+
+	wantStatus := map[string]string{
+		"ok":      CheckOK,
+		"partial": CheckWarning,
+		"typo":    CheckFailed,
+		"empty":   CheckWarning,
+		"warned":  CheckWarning,
+		"broken":  CheckFailed,
+		"matrix":  CheckOK,
+		"scalar":  CheckOK,
+	}
+	for name, want := range wantStatus {
+		if got := byName[name].Status; got != want {
+			t.Errorf("%s 状态期望 %s，实际 %s: %+v", name, want, got, byName[name])
+		}
+	}
+
+	ok := byName["ok"]
+	if ok.ResultType != "vector" || ok.SeriesCount != 1 || len(ok.Samples) != 1 || !strings.Contains(ok.Samples[0], "=> 1") {
+		t.Errorf("正常查询的检查结果不正确: %+v", ok)
+	}
+	if matrix := byName["matrix"]; matrix.ResultType != "matrix" || len(matrix.Samples) != 1 || !strings.Contains(matrix.Samples[0], "=> 3") {
+		t.Errorf("范围向量的检查结果不正确: %+v", matrix)
+	}
+	if scalar := byName["scalar"]; scalar.ResultType != "scalar" || scalar.SeriesCount != 1 || len(scalar.MissingLabels) != 0 {
+		t.Errorf("标量的检查结果不正确: %+v", scalar)
+	}
+	partial := byName["partial"]
+	if len(partial.MissingLabels) != 1 || partial.MissingLabels[0] != (MissingLabel{Name: "mountpoint", Series: 1}) {
+		t.Errorf("缺失标签统计不正确: %+v", partial.MissingLabels)
+	}
+	if warned := byName["warned"]; len(warned.Warnings) != 1 || warned.Warnings[0] != "partial response" {
+		t.Errorf("应记录 Prometheus 警告: %+v", warned)
+	}
+	if broken := byName["broken"]; broken.Error != "parse error" || !broken.Failed() {
+		t.Errorf("查询错误应记录在检查结果中: %+v", broken)
+	}
+}
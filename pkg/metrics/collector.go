@@ -2,10 +2,13 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"html/template"
 	"log"
+	"math"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -13,12 +16,15 @@ import (
 
 	"PromAI/pkg/config"
 	"PromAI/pkg/report"
+	"PromAI/pkg/threshold"
+	"PromAI/pkg/units"
 )
 
 // Collector 处理指标收集
 type Collector struct {
-	Client PrometheusAPI
-	config *config.Config
+	Client      PrometheusAPI            // 默认数据源客户端
+	Datasources map[string]PrometheusAPI // 按名称索引的数据源客户端
+	config      *config.Config
 }
 
 type PrometheusAPI interface {
@@ -29,212 +35,733 @@ type PrometheusAPI interface {
 // NewCollector 创建新的收集器
 func NewCollector(client PrometheusAPI, config *config.Config) *Collector {
 	return &Collector{
-		Client: client,
-		config: config,
+		Client:      client,
+		Datasources: make(map[string]PrometheusAPI),
+		config:      config,
 	}
 }
 
+// AddDatasource 注册命名数据源
+func (c *Collector) AddDatasource(name string, client PrometheusAPI) {
+	c.Datasources[name] = client
+}
+
+// ClientFor 返回指标使用的数据源名称及对应客户端
+func (c *Collector) ClientFor(metricType config.MetricType, metric config.MetricConfig) (string, PrometheusAPI, error) {
+	name := c.config.DatasourceFor(metricType, metric)
+	if client, exists := c.Datasources[name]; exists {
+		return name, client, nil
+	}
+	if name == c.config.DefaultDatasource() && c.Client != nil {
+		return name, c.Client, nil
+	}
+	return name, nil, fmt.Errorf("未知数据源: %s", name)
+}
+
+// legacyHostGroup 旧版配置中固定用于主机资源概览的分组名
+const legacyHostGroup = "基础资源使用情况"
+
+// legacyHostRoles 旧版配置中按指标名识别主机资源角色，仅在未配置 role 时使用
+var legacyHostRoles = map[string]string{
+	"CPU使用率":       report.RoleCPUUsage,
+	"CPU核心数":       report.RoleCPUCount,
+	"内存总量":         report.RoleMemTotal,
+	"内存使用量":        report.RoleMemUsed,
+	"内存使用率":        report.RoleMemUsage,
+	"运行时间":         report.RoleUptime,
+	"5分钟负载":        report.RoleLoad5,
+	"TCP连接数":       report.RoleTCPConnections,
+	"TCP_TW数":      report.RoleTCPTimeWait,
+	"30分钟内磁盘平均读取值": report.RoleDiskRead,
+	"30分钟内磁盘平均写入值": report.RoleDiskWrite,
+	"30分钟内下载速率":    report.RoleNetRx,
+	"30分钟内上传速率":    report.RoleNetTx,
+	"磁盘总量":         report.RoleDiskTotal,
+	"磁盘使用量":        report.RoleDiskUsed,
+	"磁盘使用率":        report.RoleDiskUsage,
+}
+
+// metricRole 返回指标角色，兼容未配置 role 的旧版配置
+func metricRole(groupType string, metric config.MetricConfig) string {
+	if metric.Role != "" {
+		return metric.Role
+	}
+	if groupType == legacyHostGroup {
+		return legacyHostRoles[metric.Name]
+	}
+	return ""
+}
+
+const (
+	defaultConcurrency  = 5                // 默认并发查询数
+	defaultQueryTimeout = 30 * time.Second // 默认单个查询超时时间
+	defaultTotalTimeout = 5 * time.Minute  // 默认整体收集超时时间
+
+	defaultTrendRange  = 24 * time.Hour // 默认趋势时间范围
+	defaultTrendPoints = 120            // 未配置 trend_step 时每条趋势的采样点数
+	minTrendStep       = time.Minute    // 自动计算的最小采样间隔
+)
+
+// metricJob 单个指标的查询任务
+type metricJob struct {
+	groupIndex  int
+	metricIndex int
+	metricType  config.MetricType
+	metric      config.MetricConfig
+}
+
+// metricResult 单个指标的查询结果
+type metricResult struct {
+	metrics []report.MetricData
+	err     *report.QueryError
+	quality qualityStats
+	trend   *report.MetricTrend
+}
+
+// qualityStats 数据质量统计
+type qualityStats struct {
+	total         int
+	valid         int
+	invalid       int
+	diskAnomalies int
+	missingLabels int      // 缺少配置标签的样本数
+	dropped       int      // 因缺少标签被丢弃的样本数
+	labelNames    []string // 缺失的标签名，仅单个指标的统计记录
+}
+
+func (q *qualityStats) add(other qualityStats) {
+	q.total += other.total
+	q.valid += other.valid
+	q.invalid += other.invalid
+	q.diskAnomalies += other.diskAnomalies
+	q.missingLabels += other.missingLabels
+	q.dropped += other.dropped
+}
+
+// addMissingLabels 记录缺失的标签名，已记录的不重复添加
+func (q *qualityStats) addMissingLabels(names []string) {
+	for _, name := range names {
+		if !slices.Contains(q.labelNames, name) {
+			q.labelNames = append(q.labelNames, name)
+		}
+	}
+}
+
+// Progress 收集进度，每个指标组全部查询完成时上报一次
+type Progress struct {
+	Group        string `json:"group"`         // 刚完成的指标组
+	GroupsDone   int    `json:"groups_done"`   // 已完成的指标组数
+	GroupsTotal  int    `json:"groups_total"`  // 指标组总数
+	MetricsDone  int    `json:"metrics_done"`  // 已完成的指标数
+	MetricsTotal int    `json:"metrics_total"` // 指标总数
+}
+
+// ProgressFunc 收集进度回调
+type ProgressFunc func(Progress)
+
 // CollectMetrics 收集指标数据
 func (c *Collector) CollectMetrics() (*report.ReportData, error) {
-	ctx := context.Background()
+	return c.CollectMetricsContext(context.Background())
+}
+
+// CollectMetricsContext 在给定上下文中并发收集指标数据，结果按配置顺序返回
+func (c *Collector) CollectMetricsContext(ctx context.Context) (*report.ReportData, error) {
+	return c.CollectMetricsWithProgress(ctx, nil)
+}
+
+// CollectMetricsWithProgress 与 CollectMetricsContext 相同，并在每个指标组完成时回调 progress
+func (c *Collector) CollectMetricsWithProgress(ctx context.Context, progress ProgressFunc) (*report.ReportData, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.totalTimeout())
+	defer cancel()
 
 	data := &report.ReportData{
 		Timestamp:    time.Now(),
 		MetricGroups: make(map[string]*report.MetricGroup),
 		GroupOrder:   make([]string, 0, len(c.config.MetricTypes)),
-		ChartData:    make(map[string]template.JS),
 		Project:      c.config.ProjectName,
+		HostLabels:   c.config.HostSummary.WithDefaults(),
+		Comparison:   c.config.Comparison.WithDefaults(),
+	}
+
+	// 按配置顺序预留结果位置，保证并发查询后仍按配置顺序输出
+	results := make([][]metricResult, len(c.config.MetricTypes))
+	jobs := make(chan metricJob)
+	tracker := newProgressTracker(c.config.MetricTypes, progress)
+	for i, metricType := range c.config.MetricTypes {
+		results[i] = make([]metricResult, len(metricType.Metrics))
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < c.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job.groupIndex][job.metricIndex] = c.collectMetric(ctx, job)
+				tracker.done(job.groupIndex)
+			}
+		}()
 	}
 
+	for i, metricType := range c.config.MetricTypes {
+		for j, metric := range metricType.Metrics {
+			jobs <- metricJob{groupIndex: i, metricIndex: j, metricType: metricType, metric: metric}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
 	// 添加数据质量统计
-	totalMetrics := 0
-	validMetrics := 0
-	invalidMetrics := 0
-	diskAnomalies := 0
+	var quality qualityStats
+	var metricQuality []report.MetricQuality
+	usedDatasources := make(map[string]bool)
 
-	for _, metricType := range c.config.MetricTypes {
+	for i, metricType := range c.config.MetricTypes {
 		group := &report.MetricGroup{
 			Type:          metricType.Type,
 			MetricsByName: make(map[string][]report.MetricData),
 			MetricOrder:   make([]string, 0, len(metricType.Metrics)),
+			Errors:        make(map[string]report.QueryError),
+			Trends:        make(map[string]*report.MetricTrend),
 		}
 		data.MetricGroups[metricType.Type] = group
 		data.GroupOrder = append(data.GroupOrder, metricType.Type)
 
-		for _, metric := range metricType.Metrics {
-			result, _, err := c.Client.Query(ctx, metric.Query, time.Now())
-			if err != nil {
-				log.Printf("警告: 查询指标 %s 失败: %v, PromQL: %s", metric.Name, err, metric.Query)
-				continue
+		for j, metric := range metricType.Metrics {
+			result := results[i][j]
+			quality.add(result.quality)
+			if q := result.quality; q.invalid > 0 || q.missingLabels > 0 {
+				metricQuality = append(metricQuality, report.MetricQuality{
+					Group:         metricType.Type,
+					Metric:        metric.Name,
+					Policy:        missingLabelPolicy(metric),
+					Invalid:       q.invalid,
+					MissingLabels: q.missingLabels,
+					Dropped:       q.dropped,
+					Labels:        q.labelNames,
+				})
 			}
-			log.Printf("指标 [%s] 查询结果: %+v", metric.Name, result)
-
-			switch v := result.(type) {
-			case model.Vector:
-				metrics := make([]report.MetricData, 0, len(v))
-				for _, sample := range v {
-					totalMetrics++
-					log.Printf("指标 [%s] 原始数据: %+v, 值: %+v", metric.Name, sample.Metric, sample.Value)
-
-					// 数据验证：在处理数据前先验证数值的合理性
-					if err := validateMetricValue(metric.Name, float64(sample.Value)); err != nil {
-						invalidMetrics++
-						if strings.Contains(metric.Name, "磁盘") {
-							diskAnomalies++
-						}
-						log.Printf("警告: 指标 [%s] 数据验证失败: %v, 原始值: %f", metric.Name, err, float64(sample.Value))
-						continue // 跳过异常数据
-					}
-					validMetrics++
-
-					availableLabels := make(map[string]string)
-					for labelName, labelValue := range sample.Metric {
-						availableLabels[string(labelName)] = string(labelValue)
-					}
-
-					labels := make([]report.LabelData, 0, len(metric.Labels))
-					for configLabel, configAlias := range metric.Labels {
-						labelValue := "-"
-						if rawValue, exists := availableLabels[configLabel]; exists && rawValue != "" {
-							labelValue = rawValue
-						} else {
-							log.Printf("警告: 指标 [%s] 标签 [%s] 缺失或为空", metric.Name, configLabel)
-						}
-
-						labels = append(labels, report.LabelData{
-							Name:  configLabel,
-							Alias: configAlias,
-							Value: labelValue,
-						})
-					}
-
-					if !validateLabels(labels) {
-						log.Printf("警告: 指标 [%s] 标签数据不完整，跳过该条记录", metric.Name)
-						continue
-					}
-
-					metricData := report.MetricData{
-						Name:        metric.Name,
-						Description: metric.Description,
-						Value:       float64(sample.Value),
-						Threshold:   metric.Threshold,
-						Unit:        metric.Unit,
-						Status:      getStatus(float64(sample.Value), metric.Threshold, metric.ThresholdType, metric.Type),
-						StatusText:  report.GetStatusText(getStatus(float64(sample.Value), metric.Threshold, metric.ThresholdType, metric.Type)),
-						Timestamp:   time.Now(),
-						Labels:      labels,
-					}
-
-					if err := validateMetricData(metricData, metric.Labels); err != nil {
-						log.Printf("警告: 指标 [%s] 数据验证失败: %v", metric.Name, err)
-						continue
-					}
-
-					metrics = append(metrics, metricData)
-				}
-				// 存储所有指标数据到MetricsByName（包括show_in_table=false的）
-				group.MetricsByName[metric.Name] = metrics
 
-				// 只有show_in_table为true或未设置的指标才添加到MetricOrder
-				// 默认行为：如果show_in_table为nil（未设置），则显示；如果显式设置为true，则显示
-				if metric.ShowInTable == nil || *metric.ShowInTable {
-					group.MetricOrder = append(group.MetricOrder, metric.Name)
-				}
+			if datasource := c.config.DatasourceFor(metricType, metric); !usedDatasources[datasource] {
+				usedDatasources[datasource] = true
+				data.Datasources = append(data.Datasources, datasource)
+			}
+
+			if result.err != nil {
+				group.Errors[metric.Name] = *result.err
+				data.QueryErrors = append(data.QueryErrors, *result.err)
+			}
+
+			// 存储所有指标数据到MetricsByName（包括show_in_table=false的）
+			group.MetricsByName[metric.Name] = result.metrics
+			if result.trend != nil {
+				group.Trends[metric.Name] = result.trend
+				group.TrendOrder = append(group.TrendOrder, metric.Name)
+			}
+
+			// 只有show_in_table为true或未设置的指标才添加到MetricOrder
+			// 默认行为：如果show_in_table为nil（未设置），则显示；如果显式设置为true，则显示
+			if metric.ShowInTable == nil || *metric.ShowInTable {
+				group.MetricOrder = append(group.MetricOrder, metric.Name)
 			}
 		}
 	}
 
-	// 输出数据质量统计报告
-	log.Printf("数据收集统计 - 总指标数: %d, 有效数: %d, 无效数: %d, 磁盘异常: %d",
-		totalMetrics, validMetrics, invalidMetrics, diskAnomalies)
+	// 数据质量统计，在报告中单独展示
+	data.Quality = report.DataQuality{
+		Total:         quality.total,
+		Valid:         quality.valid,
+		Invalid:       quality.invalid,
+		DiskAnomalies: quality.diskAnomalies,
+		MissingLabels: quality.missingLabels,
+		Dropped:       quality.dropped,
+		QueryErrors:   len(data.QueryErrors),
+		Metrics:       metricQuality,
+	}
+	log.Printf("数据收集统计 - 总指标数: %d, 有效数: %d, 无效数: %d, 磁盘异常: %d, 缺少标签: %d, 丢弃: %d, 查询失败: %d",
+		quality.total, quality.valid, quality.invalid, quality.diskAnomalies, quality.missingLabels, quality.dropped, len(data.QueryErrors))
 	return data, nil
 }
 
-// validateMetricData 验证指标数据的完整性
-func validateMetricData(data report.MetricData, configLabels map[string]string) error {
-	if len(data.Labels) != len(configLabels) {
-		return fmt.Errorf("标签数量不匹配: 期望 %d, 实际 %d",
-			len(configLabels), len(data.Labels))
+// progressTracker 统计各指标组剩余的查询数，组内全部完成时上报进度
+type progressTracker struct {
+	mu        sync.Mutex
+	fn        ProgressFunc
+	groups    []string
+	remaining []int
+	state     Progress
+}
+
+func newProgressTracker(metricTypes []config.MetricType, fn ProgressFunc) *progressTracker {
+	t := &progressTracker{
+		fn:        fn,
+		groups:    make([]string, len(metricTypes)),
+		remaining: make([]int, len(metricTypes)),
+	}
+	t.state.GroupsTotal = len(metricTypes)
+	for i, metricType := range metricTypes {
+		t.groups[i] = metricType.Type
+		t.remaining[i] = len(metricType.Metrics)
+		t.state.MetricsTotal += len(metricType.Metrics)
+		// 没有指标的分组视为已完成
+		if len(metricType.Metrics) == 0 {
+			t.state.GroupsDone++
+		}
 	}
+	return t
+}
 
-	labelMap := make(map[string]bool)
-	for _, label := range data.Labels {
-		if _, exists := configLabels[label.Name]; !exists {
-			return fmt.Errorf("发现未配置的标签: %s", label.Name)
+func (t *progressTracker) done(groupIndex int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.MetricsDone++
+	t.remaining[groupIndex]--
+	if t.remaining[groupIndex] > 0 {
+		return
+	}
+	t.state.GroupsDone++
+	t.state.Group = t.groups[groupIndex]
+	if t.fn != nil {
+		t.fn(t.state)
+	}
+}
+
+// collectMetric 执行单个指标查询并转换结果
+func (c *Collector) collectMetric(ctx context.Context, job metricJob) metricResult {
+	metric := job.metric
+	queryError := func(err error, timeout bool) metricResult {
+		return metricResult{
+			metrics: []report.MetricData{},
+			err: &report.QueryError{
+				Group:      job.metricType.Type,
+				Metric:     metric.Name,
+				Datasource: c.config.DatasourceFor(job.metricType, metric),
+				Query:      metric.Query,
+				Error:      err.Error(),
+				Timeout:    timeout,
+			},
 		}
-		if label.Value == "" || label.Value == "-" {
-			return fmt.Errorf("标签 %s 值为空", label.Name)
+	}
+
+	// 整体收集已超时，剩余指标不再发起查询
+	if err := ctx.Err(); err != nil {
+		log.Printf("警告: 指标 %s 未执行查询: 收集已超时", metric.Name)
+		return queryError(fmt.Errorf("收集超时，未执行查询: %w", err), true)
+	}
+
+	datasource, client, err := c.ClientFor(job.metricType, metric)
+	if err != nil {
+		log.Printf("警告: 指标 %s 无法查询: %v", metric.Name, err)
+		return queryError(err, false)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, c.queryTimeout(metric))
+	defer cancel()
+
+	result, warnings, err := client.Query(queryCtx, metric.Query, time.Now())
+	if err != nil {
+		timeout := errors.Is(queryCtx.Err(), context.DeadlineExceeded)
+		log.Printf("警告: 查询指标 %s 失败: %v, PromQL: %s", metric.Name, err, metric.Query)
+		return queryError(err, timeout)
+	}
+	if len(warnings) > 0 {
+		log.Printf("警告: 指标 [%s] 查询返回了 Prometheus 警告: %v", metric.Name, warnings)
+	}
+	log.Printf("指标 [%s] 查询结果: %+v", metric.Name, result)
+
+	rules := c.thresholdRules(ctx, client, metric)
+
+	var metrics []report.MetricData
+	var quality qualityStats
+	switch v := result.(type) {
+	case model.Vector:
+		metrics, quality = buildVectorMetrics(metric, v, rules)
+	case *model.Scalar:
+		metrics, quality = buildScalarMetrics(metric, v, rules)
+	case model.Matrix:
+		metrics, quality = buildVectorMetrics(metric, summarizeMatrix(metric, v), rules)
+	case *model.String:
+		metrics, quality = buildStringMetrics(metric, v)
+	default:
+		log.Printf("警告: 指标 [%s] 查询返回了意外的结果类型: %T", metric.Name, result)
+		metrics = []report.MetricData{}
+	}
+	// 期望存在但缺失的序列显示为异常行
+	metrics = append(metrics, c.missingSeries(ctx, client, metric, result)...)
+
+	role := metricRole(job.metricType.Type, metric)
+	for i := range metrics {
+		metrics[i].Role = role
+		metrics[i].Datasource = datasource
+	}
+	return metricResult{
+		metrics: metrics,
+		quality: quality,
+		trend:   c.collectTrend(ctx, client, metric, role, datasource),
+	}
+}
+
+// collectTrend 执行 trend_query 范围查询，失败原因记录在趋势数据中，不影响即时查询结果
+func (c *Collector) collectTrend(ctx context.Context, client PrometheusAPI, metric config.MetricConfig, role, datasource string) *report.MetricTrend {
+	if metric.TrendQuery == "" {
+		return nil
+	}
+
+	trendRange := defaultTrendRange
+	if metric.TrendRange > 0 {
+		trendRange = metric.TrendRange.Std()
+	}
+	step := metric.TrendStep.Std()
+	if step <= 0 {
+		step = trendRange / defaultTrendPoints
+		if step < minTrendStep {
+			step = minTrendStep
 		}
-		labelMap[label.Name] = true
 	}
+	end := time.Now().Add(-metric.TrendLookback.Std())
 
-	return nil
+	// 配置了 target_unit 且可换算时，趋势数值按目标单位绘制
+	unit, factor := metric.Unit, 1.0
+	if metric.TargetUnit != "" {
+		if converted, err := units.Convert(1, metric.Unit, metric.TargetUnit); err == nil {
+			unit, factor = metric.TargetUnit, converted
+		}
+	}
+
+	trend := &report.MetricTrend{
+		Start:      end.Add(-trendRange),
+		End:        end,
+		Step:       step,
+		Unit:       unit,
+		Role:       role,
+		Datasource: datasource,
+		Series:     []report.TrendSeries{},
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, c.queryTimeout(metric))
+	defer cancel()
+
+	result, _, err := client.QueryRange(queryCtx, metric.TrendQuery, v1.Range{Start: trend.Start, End: trend.End, Step: step})
+	if err != nil {
+		log.Printf("警告: 指标 [%s] 趋势查询失败: %v, PromQL: %s", metric.Name, err, metric.TrendQuery)
+		trend.Error = err.Error()
+		return trend
+	}
+
+	switch v := result.(type) {
+	case model.Matrix:
+		for _, stream := range v {
+			availableLabels := make(map[string]string, len(stream.Metric))
+			for name, value := range stream.Metric {
+				availableLabels[string(name)] = string(value)
+			}
+			series := report.TrendSeries{
+				Labels: buildLabels(metric, availableLabels),
+				Points: make([]report.TrendPoint, 0, len(stream.Values)),
+			}
+			for _, sample := range stream.Values {
+				value := float64(sample.Value)
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					continue
+				}
+				series.Points = append(series.Points, report.TrendPoint{
+					Time:  sample.Timestamp.Time(),
+					Value: units.Scale(value, metric.ScaleFactor) * factor,
+				})
+			}
+			trend.Series = append(trend.Series, series)
+		}
+	case nil:
+	default:
+		log.Printf("警告: 指标 [%s] 趋势查询返回了意外的结果类型: %T", metric.Name, result)
+		trend.Error = fmt.Sprintf("趋势查询返回了意外的结果类型: %T", result)
+	}
+	return trend
+}
+
+// thresholdRules 查询指标的阈值规则集，threshold_query 使用独立的查询超时，不受主查询耗时影响
+func (c *Collector) thresholdRules(ctx context.Context, client PrometheusAPI, metric config.MetricConfig) *threshold.RuleSet {
+	thresholdCtx, cancel := context.WithTimeout(ctx, c.queryTimeout(metric))
+	defer cancel()
+	return ThresholdRules(thresholdCtx, client, metric, time.Now())
 }
 
-// getStatus 获取状态
-func getStatus(value, threshold float64, thresholdType, metricType string) string {
-	// 展示类指标直接返回normal状态
-	if metricType == "display" {
-		return "normal"
+// ThresholdRules 返回指标的阈值规则集；配置了 threshold_query 时查询 ts 时刻的逐序列阈值，查询失败时退回配置的阈值
+func ThresholdRules(ctx context.Context, client PrometheusAPI, metric config.MetricConfig, ts time.Time) *threshold.RuleSet {
+	rules, err := metric.ThresholdRules()
+	if err != nil {
+		log.Printf("警告: 指标 [%s] 阈值覆盖配置无效，使用默认阈值: %v", metric.Name, err)
+		rules, _ = threshold.NewRuleSet(metric.ThresholdRule(), nil)
+	}
+	if metric.ThresholdQuery == "" || metric.Type == "display" {
+		return rules
 	}
 
-	// 监控类指标进行阈值判断
-	if thresholdType == "" {
-		thresholdType = "greater"
+	result, _, err := client.Query(ctx, metric.ThresholdQuery, ts)
+	if err != nil {
+		log.Printf("警告: 指标 [%s] 查询阈值失败，使用配置的阈值: %v, PromQL: %s", metric.Name, err, metric.ThresholdQuery)
+		return rules
+	}
+	v, ok := result.(model.Vector)
+	if !ok {
+		log.Printf("警告: 指标 [%s] 阈值查询返回了意外的结果类型: %T", metric.Name, result)
+		return rules
+	}
+
+	series := make([]threshold.SeriesThreshold, 0, len(v))
+	for _, sample := range v {
+		labels := make(map[string]string, len(sample.Metric))
+		for name, value := range sample.Metric {
+			labels[string(name)] = string(value)
+		}
+		// 阈值与指标值使用相同的缩放因子
+		series = append(series, threshold.SeriesThreshold{
+			Labels: labels,
+			Value:  units.Scale(float64(sample.Value), metric.ScaleFactor),
+		})
 	}
-	switch thresholdType {
-	case "greater":
-		if value > threshold {
-			return "critical"
-		} else if value >= threshold*0.8 {
-			return "warning"
+	rules.SetSeriesThresholds(series)
+	return rules
+}
+
+// buildVectorMetrics 将即时向量转换为报告数据
+func buildVectorMetrics(metric config.MetricConfig, v model.Vector, rules *threshold.RuleSet) ([]report.MetricData, qualityStats) {
+	var quality qualityStats
+	policy := missingLabelPolicy(metric)
+	metrics := make([]report.MetricData, 0, len(v))
+	for _, sample := range v {
+		quality.total++
+		log.Printf("指标 [%s] 原始数据: %+v, 值: %+v", metric.Name, sample.Metric, sample.Value)
+
+		// 数据验证：在处理数据前先验证数值的合理性
+		if err := validateMetricValue(metric.Name, float64(sample.Value)); err != nil {
+			quality.invalid++
+			if strings.Contains(metric.Name, "磁盘") {
+				quality.diskAnomalies++
+			}
+			log.Printf("警告: 指标 [%s] 数据验证失败: %v, 原始值: %f", metric.Name, err, float64(sample.Value))
+			continue // 跳过异常数据
 		}
-	case "greater_equal":
-		if value >= threshold {
-			return "critical"
-		} else if value >= threshold*0.8 {
-			return "warning"
+		quality.valid++
+
+		availableLabels := make(map[string]string)
+		for labelName, labelValue := range sample.Metric {
+			availableLabels[string(labelName)] = string(labelValue)
 		}
-	case "less":
-		if value < threshold {
-			return "normal"
-		} else if value <= threshold*1.2 {
-			return "warning"
+
+		labels := buildLabels(metric, availableLabels)
+		missing := missingLabelNames(labels)
+		if len(missing) > 0 {
+			quality.missingLabels++
+			quality.addMissingLabels(missing)
+			if policy == config.MissingLabelDrop {
+				quality.dropped++
+				log.Printf("警告: 指标 [%s] 标签数据不完整，跳过该条记录", metric.Name)
+				continue
+			}
 		}
-	case "less_equal":
-		if value <= threshold {
-			return "normal"
-		} else if value <= threshold*1.2 {
-			return "warning"
+
+		// 先应用缩放因子，阈值按缩放后的值（unit 单位）判断
+		value := units.Scale(float64(sample.Value), metric.ScaleFactor)
+		rule, source := rules.For(availableLabels)
+		// 与健康看板共用同一套规则，展示类指标始终为正常
+		status := rule.Evaluate(value)
+		metricData := report.MetricData{
+			Name:        metric.Name,
+			Description: metric.Description,
+			Value:       value,
+			Threshold:   rule.Threshold,
+			Unit:        metric.Unit,
+			Display:     units.Format(value, metric.Unit, metric.TargetUnit, metric.FormatType),
+			Status:      status,
+			StatusText:  report.GetStatusText(status),
+			Timestamp:   time.Now(),
+			Labels:      labels,
 		}
-	case "equal":
-		if value == threshold {
-			return "normal"
-		} else if value > threshold {
-			return "critical"
+		if metric.Type != "display" {
+			metricData.ThresholdText = rule.Describe(metric.Unit)
+			metricData.ThresholdRule = source
 		}
-		return "critical"
-	// 新增下限保障型操作符
-	case "at_least":
-		if value >= threshold {
-			return "normal"
-		} else if value >= threshold*0.8 {
-			return "warning"
+		if policy == config.MissingLabelFlag {
+			metricData.MissingLabels = missing
 		}
-		return "critical"
+
+		if err := validateMetricData(metricData, metric.Labels, policy != config.MissingLabelDrop); err != nil {
+			log.Printf("警告: 指标 [%s] 数据验证失败: %v", metric.Name, err)
+			continue
+		}
+
+		metrics = append(metrics, metricData)
 	}
+	return metrics, quality
+}
 
-	return "normal"
+// buildScalarMetrics 将标量结果转换为一行不带标签的报告数据，阈值使用指标的默认规则
+func buildScalarMetrics(metric config.MetricConfig, s *model.Scalar, rules *threshold.RuleSet) ([]report.MetricData, qualityStats) {
+	// 标量没有标签，不按配置的 labels 提取；标签列统一显示 "-"，与同一指标的其他行保持表头一致
+	unlabeled := metric
+	unlabeled.Labels = nil
+	metrics, quality := buildVectorMetrics(unlabeled, model.Vector{&model.Sample{Value: s.Value, Timestamp: s.Timestamp}}, rules)
+	for i := range metrics {
+		metrics[i].Labels = placeholderLabels(metric)
+	}
+	return metrics, quality
 }
 
-// validateLabels 验证标签数据的完整性
-func validateLabels(labels []report.LabelData) bool {
+// summarizeMatrix 按 matrix_aggregation 将范围向量的每条序列汇总为一个采样点，没有有效采样点的序列被忽略
+func summarizeMatrix(metric config.MetricConfig, m model.Matrix) model.Vector {
+	v := make(model.Vector, 0, len(m))
+	for _, stream := range m {
+		var values []float64
+		var last model.Time
+		for _, sample := range stream.Values {
+			value := float64(sample.Value)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			values = append(values, value)
+			last = sample.Timestamp
+		}
+		if len(values) == 0 {
+			log.Printf("警告: 指标 [%s] 序列 %s 在查询范围内没有有效采样点", metric.Name, stream.Metric)
+			continue
+		}
+
+		summary := values[len(values)-1]
+		switch metric.MatrixAggregation {
+		case config.MatrixAggregationMin:
+			for _, value := range values {
+				summary = math.Min(summary, value)
+			}
+		case config.MatrixAggregationMax:
+			for _, value := range values {
+				summary = math.Max(summary, value)
+			}
+		case config.MatrixAggregationAvg:
+			sum := 0.0
+			for _, value := range values {
+				sum += value
+			}
+			summary = sum / float64(len(values))
+		}
+		v = append(v, &model.Sample{Metric: stream.Metric, Value: model.SampleValue(summary), Timestamp: last})
+	}
+	return v
+}
+
+// buildStringMetrics 将字符串结果转换为一行仅用于展示的报告数据，不参与阈值判断和图表
+func buildStringMetrics(metric config.MetricConfig, s *model.String) ([]report.MetricData, qualityStats) {
+	return []report.MetricData{{
+		Name:        metric.Name,
+		Description: metric.Description,
+		Unit:        metric.Unit,
+		Display:     s.Value,
+		Text:        true,
+		Status:      threshold.StatusNormal,
+		StatusText:  report.GetStatusText(threshold.StatusNormal),
+		Timestamp:   time.Now(),
+		Labels:      placeholderLabels(metric),
+	}}, qualityStats{total: 1, valid: 1}
+}
+
+// placeholderLabels 按配置的标签顺序及别名生成标签，值均为 "-"，用于没有标签的结果行
+func placeholderLabels(metric config.MetricConfig) []report.LabelData {
+	labels := make([]report.LabelData, 0, len(metric.Labels))
+	for _, configLabel := range metric.Labels {
+		labels = append(labels, report.LabelData{
+			Name:  configLabel.Name,
+			Alias: configLabel.Alias,
+			Value: report.MissingLabelValue,
+		})
+	}
+	return labels
+}
+
+// buildLabels 按配置的标签顺序及别名提取序列的标签值，缺失的标签值为 "-"
+func buildLabels(metric config.MetricConfig, availableLabels map[string]string) []report.LabelData {
+	labels := make([]report.LabelData, 0, len(metric.Labels))
+	for _, configLabel := range metric.Labels {
+		labelValue := report.MissingLabelValue
+		if rawValue, exists := availableLabels[configLabel.Name]; exists && rawValue != "" {
+			labelValue = rawValue
+		} else {
+			log.Printf("警告: 指标 [%s] 标签 [%s] 缺失或为空", metric.Name, configLabel.Name)
+		}
+
+		labels = append(labels, report.LabelData{
+			Name:  configLabel.Name,
+			Alias: configLabel.Alias,
+			Value: labelValue,
+		})
+	}
+	return labels
+}
+
+// concurrency 返回并发查询数
+func (c *Collector) concurrency() int {
+	if c.config.Collection.Concurrency > 0 {
+		return c.config.Collection.Concurrency
+	}
+	return defaultConcurrency
+}
+
+// totalTimeout 返回整体收集超时时间
+func (c *Collector) totalTimeout() time.Duration {
+	if c.config.Collection.Timeout > 0 {
+		return c.config.Collection.Timeout.Std()
+	}
+	return defaultTotalTimeout
+}
+
+// queryTimeout 返回单个指标的查询超时时间
+func (c *Collector) queryTimeout(metric config.MetricConfig) time.Duration {
+	if metric.Timeout > 0 {
+		return metric.Timeout.Std()
+	}
+	if c.config.Collection.QueryTimeout > 0 {
+		return c.config.Collection.QueryTimeout.Std()
+	}
+	return defaultQueryTimeout
+}
+
+// validateMetricData 验证指标数据的完整性，allowMissing 为 true 时允许标签值为空
+func validateMetricData(data report.MetricData, configLabels config.Labels, allowMissing bool) error {
+	if len(data.Labels) != len(configLabels) {
+		return fmt.Errorf("标签数量不匹配: 期望 %d, 实际 %d",
+			len(configLabels), len(data.Labels))
+	}
+
+	labelMap := make(map[string]bool)
+	for _, label := range data.Labels {
+		if !configLabels.Has(label.Name) {
+			return fmt.Errorf("发现未配置的标签: %s", label.Name)
+		}
+		if !allowMissing && (label.Value == "" || label.Value == report.MissingLabelValue) {
+			return fmt.Errorf("标签 %s 值为空", label.Name)
+		}
+		labelMap[label.Name] = true
+	}
+
+	return nil
+}
+
+// missingLabelNames 返回值缺失或为空的标签名
+func missingLabelNames(labels []report.LabelData) []string {
+	var names []string
 	for _, label := range labels {
-		if label.Value == "" || label.Value == "-" {
-			return false
+		if label.Value == "" || label.Value == report.MissingLabelValue {
+			names = append(names, label.Name)
 		}
 	}
-	return true
+	return names
+}
+
+// missingLabelPolicy 返回指标生效的 missing_label_policy
+func missingLabelPolicy(metric config.MetricConfig) string {
+	if metric.MissingLabelPolicy == "" {
+		return config.MissingLabelDrop
+	}
+	return metric.MissingLabelPolicy
 }
 
 // validateDiskData 验证磁盘相关数据的合理性
@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/prometheus/common/model"
 
 	"PromAI/pkg/config"
+	"PromAI/pkg/report"
+	"PromAI/pkg/threshold"
 )
 
 // boolPtr 返回bool指针的辅助函数
@@ -16,14 +20,41 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
-// MockPrometheusAPI 模拟Prometheus API
+// MockPrometheusAPI 模拟Prometheus API，可按查询预设结果、警告、错误和耗时
 type MockPrometheusAPI struct {
 	responses map[string]model.Value
+	warnings  map[string]v1.Warnings
+	errs      map[string]error
+	// delays 模拟查询耗时，上下文先结束时返回上下文的错误
+	delays map[string]time.Duration
+
+	mu     sync.Mutex
+	ranges []v1.Range // 记录范围查询的窗口
+}
+
+// wait 模拟查询耗时，上下文先结束时返回错误
+func (m *MockPrometheusAPI) wait(ctx context.Context, query string) error {
+	delay, exists := m.delays[query]
+	if !exists {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (m *MockPrometheusAPI) Query(ctx context.Context, query string, ts time.Time, opts ...v1.Option) (model.Value, v1.Warnings, error) {
+	if err := m.wait(ctx, query); err != nil {
+		return nil, nil, err
+	}
+	if err, exists := m.errs[query]; exists {
+		return nil, nil, err
+	}
 	if response, exists := m.responses[query]; exists {
-		return response, nil, nil
+		return response, m.warnings[query], nil
 	}
 	// 返回默认的Vector值
 	return model.Vector{
@@ -34,11 +65,21 @@ func (m *MockPrometheusAPI) Query(ctx context.Context, query string, ts time.Tim
 			Value:     42.0,
 			Timestamp: model.TimeFromUnix(ts.Unix()),
 		},
-	}, nil, nil
+	}, m.warnings[query], nil
 }
 
 func (m *MockPrometheusAPI) QueryRange(ctx context.Context, query string, r v1.Range, opts ...v1.Option) (model.Value, v1.Warnings, error) {
-	return nil, nil, nil
+	m.mu.Lock()
+	m.ranges = append(m.ranges, r)
+	m.mu.Unlock()
+
+	if err := m.wait(ctx, query); err != nil {
+		return nil, nil, err
+	}
+	if err, exists := m.errs[query]; exists {
+		return nil, nil, err
+	}
+	return m.responses[query], m.warnings[query], nil
 }
 
 func TestCollectorShowInTableFiltering(t *testing.T) {
@@ -55,9 +96,7 @@ func TestCollectorShowInTableFiltering(t *testing.T) {
 						Query:       "test_monitoring_query",
 						Description: "测试监控指标",
 						Unit:        "%",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 					{
 						Name:        "display-metric-hidden",
@@ -66,9 +105,7 @@ func TestCollectorShowInTableFiltering(t *testing.T) {
 						Query:       "test_display_query_hidden",
 						Description: "测试展示指标（隐藏）",
 						Unit:        "B",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 					{
 						Name:        "display-metric-shown",
@@ -77,9 +114,7 @@ func TestCollectorShowInTableFiltering(t *testing.T) {
 						Query:       "test_display_query_shown",
 						Description: "测试展示指标（显示）",
 						Unit:        "B",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 					{
 						Name: "metric-default-behavior",
@@ -88,9 +123,7 @@ func TestCollectorShowInTableFiltering(t *testing.T) {
 						Query:       "test_default_query",
 						Description: "测试默认行为指标",
 						Unit:        "%",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 				},
 			},
@@ -182,9 +215,7 @@ func TestCollectorBackwardCompatibility(t *testing.T) {
 						Query:       "test_old_config_query",
 						Description: "测试旧配置兼容性",
 						Unit:        "%",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 				},
 			},
@@ -232,9 +263,7 @@ func TestDisplayMetricStatusBehavior(t *testing.T) {
 						Unit:        "B",
 						Threshold:   100, // 设置阈值
 						ThresholdType: "greater",
-						Labels: map[string]string{
-							"instance": "节点",
-						},
+						Labels: config.Labels{{Name: "instance", Alias: "节点"}},
 					},
 				},
 			},
@@ -273,4 +302,405 @@ func TestDisplayMetricStatusBehavior(t *testing.T) {
 	if metrics[0].Status != "normal" {
 		t.Errorf("展示类指标状态应该是'normal'，实际是'%s'", metrics[0].Status)
 	}
-}
\ No newline at end of file
+}
+
+func TestCollectorConcurrentOrderAndTimeout(t *testing.T) {
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group-a",
+				Metrics: []config.MetricConfig{
+					{Name: "a1", Query: "q_a1", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+					{Name: "a2-slow", Query: "q_slow", Timeout: config.Duration(50 * time.Millisecond), Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+					{Name: "a3", Query: "q_a3", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+				},
+			},
+			{
+				Type: "group-b",
+				Metrics: []config.MetricConfig{
+					{Name: "b1", Query: "q_b1", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+				},
+			},
+		},
+	}
+	testConfig.Collection.Concurrency = 3
+
+	mockAPI := &MockPrometheusAPI{
+		responses: make(map[string]model.Value),
+		// 远超查询超时，相当于一直阻塞到上下文结束
+		delays: map[string]time.Duration{"q_slow": time.Hour},
+	}
+
+	var progress []Progress
+	reportData, err := NewCollector(mockAPI, testConfig).CollectMetricsWithProgress(context.Background(), func(p Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+
+	// 每个指标组完成时上报一次，慢查询所在的组最后完成
+	if len(progress) != 2 || progress[1].Group != "group-a" || progress[1].GroupsDone != 2 || progress[1].MetricsDone != 4 {
+		t.Errorf("收集进度不正确: %+v", progress)
+	}
+
+	if len(reportData.GroupOrder) != 2 || reportData.GroupOrder[0] != "group-a" || reportData.GroupOrder[1] != "group-b" {
+		t.Fatalf("GroupOrder不正确: %v", reportData.GroupOrder)
+	}
+
+	group := reportData.MetricGroups["group-a"]
+	expectedOrder := []string{"a1", "a2-slow", "a3"}
+	for i, name := range expectedOrder {
+		if group.MetricOrder[i] != name {
+			t.Errorf("MetricOrder[%d]不正确，期望%s，实际%s", i, name, group.MetricOrder[i])
+		}
+	}
+
+	if len(reportData.QueryErrors) != 1 {
+		t.Fatalf("期望1个查询错误，实际%d个", len(reportData.QueryErrors))
+	}
+	queryErr, exists := group.Errors["a2-slow"]
+	if !exists {
+		t.Fatal("超时指标应该记录在Errors中")
+	}
+	if !queryErr.Timeout {
+		t.Error("超时指标应该标记为Timeout")
+	}
+	if len(group.MetricsByName["a1"]) != 1 || len(group.MetricsByName["a3"]) != 1 {
+		t.Error("未超时的指标应该正常返回数据")
+	}
+}
+
+func TestCollectorRoutesQueriesByDatasource(t *testing.T) {
+	testConfig := &config.Config{
+		PrometheusURL: "http://default:9090",
+		MetricTypes: []config.MetricType{
+			{
+				Type:       "cluster-b",
+				Datasource: "b",
+				Metrics: []config.MetricConfig{
+					{Name: "group-datasource", Query: "q_group", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+					{Name: "metric-datasource", Query: "q_metric", Datasource: "default", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+					{Name: "unknown-datasource", Query: "q_unknown", Datasource: "missing", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+				},
+			},
+		},
+	}
+
+	defaultAPI := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_metric": model.Vector{&model.Sample{Metric: model.Metric{"instance": "from-default"}, Value: 1}},
+	}}
+	clusterBAPI := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_group": model.Vector{&model.Sample{Metric: model.Metric{"instance": "from-b"}, Value: 2}},
+	}}
+
+	collector := NewCollector(defaultAPI, testConfig)
+	collector.AddDatasource("b", clusterBAPI)
+
+	reportData, err := collector.CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+
+	group := reportData.MetricGroups["cluster-b"]
+	if got := group.MetricsByName["group-datasource"]; len(got) != 1 || got[0].Labels[0].Value != "from-b" || got[0].Datasource != "b" {
+		t.Errorf("分组数据源路由不正确: %+v", got)
+	}
+	if got := group.MetricsByName["metric-datasource"]; len(got) != 1 || got[0].Labels[0].Value != "from-default" || got[0].Datasource != "default" {
+		t.Errorf("指标数据源路由不正确: %+v", got)
+	}
+	if _, exists := group.Errors["unknown-datasource"]; !exists {
+		t.Error("未知数据源应该记录为查询错误")
+	}
+	if len(reportData.Datasources) != 3 {
+		t.Errorf("期望记录3个数据源，实际%v", reportData.Datasources)
+	}
+}
+
+func TestCollectorThresholdOverrides(t *testing.T) {
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "memory",
+				Metrics: []config.MetricConfig{
+					{
+						Name:          "内存使用率",
+						Query:         "q_mem",
+						Threshold:     75,
+						ThresholdType: "greater",
+						Unit:          "%",
+						Labels:        config.Labels{{Name: "instance", Alias: "节点"}},
+						ThresholdOverrides: []threshold.Override{
+							{Match: `instance=~"db-.*"`, Threshold: floatPtr(92)},
+						},
+						ThresholdQuery: "q_mem_limit",
+					},
+				},
+			},
+		},
+	}
+
+	mockAPI := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_mem": model.Vector{
+			&model.Sample{Metric: model.Metric{"instance": "app-01"}, Value: 90},
+			&model.Sample{Metric: model.Metric{"instance": "db-01"}, Value: 90},
+			&model.Sample{Metric: model.Metric{"instance": "cache-01"}, Value: 90},
+		},
+		"q_mem_limit": model.Vector{
+			&model.Sample{Metric: model.Metric{"instance": "cache-01"}, Value: 120},
+		},
+	}}
+
+	reportData, err := NewCollector(mockAPI, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+
+	want := map[string]struct {
+		status string
+		rule   string
+	}{
+		"app-01":   {"critical", threshold.SourceDefault},
+		"db-01":    {"warning", `{instance=~"db-.*"}`},
+		"cache-01": {"normal", threshold.SourceQuery},
+	}
+	metrics := reportData.MetricGroups["memory"].MetricsByName["内存使用率"]
+	if len(metrics) != 3 {
+		t.Fatalf("期望3条数据，实际%d条", len(metrics))
+	}
+	for _, m := range metrics {
+		instance := m.Labels[0].Value
+		if m.Status != want[instance].status || m.ThresholdRule != want[instance].rule {
+			t.Errorf("%s: 状态 %s 规则 %s，期望 %s %s", instance, m.Status, m.ThresholdRule, want[instance].status, want[instance].rule)
+		}
+		if m.ThresholdText == "" {
+			t.Errorf("%s: 缺少阈值说明", instance)
+		}
+	}
+}
+
+// floatPtr 返回float64指针的辅助函数
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestCollectorTrendQuery(t *testing.T) {
+	scale := 100.0
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "host",
+				Metrics: []config.MetricConfig{
+					{
+						Name:          "CPU使用率",
+						Query:         "q_cpu",
+						Unit:          "%",
+						ScaleFactor:   &scale,
+						Labels:        config.Labels{{Name: "instance", Alias: "节点"}},
+						TrendQuery:    "q_cpu_trend",
+						TrendRange:    config.Duration(6 * time.Hour),
+						TrendLookback: config.Duration(time.Hour),
+					},
+					{Name: "无趋势", Query: "q_plain", Labels: config.Labels{{Name: "instance", Alias: "节点"}}},
+				},
+			},
+		},
+	}
+
+	start := model.TimeFromUnix(time.Now().Add(-2 * time.Hour).Unix())
+	mockAPI := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_cpu_trend": model.Matrix{
+			&model.SampleStream{
+				Metric: model.Metric{"instance": "node-1"},
+				Values: []model.SamplePair{{Timestamp: start, Value: 0.5}, {Timestamp: start.Add(time.Minute), Value: 0.75}},
+			},
+		},
+	}}
+
+	reportData, err := NewCollector(mockAPI, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+
+	group := reportData.MetricGroups["host"]
+	if _, exists := group.Trends["无趋势"]; exists {
+		t.Error("未配置 trend_query 的指标不应有趋势数据")
+	}
+	if len(group.TrendOrder) != 1 || group.TrendOrder[0] != "CPU使用率" {
+		t.Errorf("TrendOrder 应只包含有趋势数据的指标: %v", group.TrendOrder)
+	}
+	trend := group.Trends["CPU使用率"]
+	if trend == nil || trend.Error != "" {
+		t.Fatalf("趋势数据不正确: %+v", trend)
+	}
+
+	if len(mockAPI.ranges) != 1 {
+		t.Fatalf("期望1次范围查询，实际%d次", len(mockAPI.ranges))
+	}
+	r := mockAPI.ranges[0]
+	if r.End.Sub(r.Start) != 6*time.Hour || r.Step != 3*time.Minute || time.Since(r.End) < time.Hour {
+		t.Errorf("趋势查询窗口不正确: %+v", r)
+	}
+
+	if len(trend.Series) != 1 || trend.Series[0].Labels[0].Value != "node-1" {
+		t.Fatalf("趋势序列不正确: %+v", trend.Series)
+	}
+	points := trend.Series[0].Points
+	if len(points) != 2 || points[0].Value != 50 || points[1].Value != 75 {
+		t.Errorf("趋势数值应应用 scale_factor: %+v", points)
+	}
+}
+
+func TestCollectorResultTypes(t *testing.T) {
+	labels := config.Labels{{Name: "instance", Alias: "节点"}}
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					{Name: "scalar", Query: "q_scalar", Threshold: 1, ThresholdType: "greater", Labels: labels},
+					{Name: "matrix-last", Query: "q_matrix", Labels: labels},
+					{Name: "matrix-max", Query: "q_matrix", MatrixAggregation: config.MatrixAggregationMax, Labels: labels},
+					{Name: "matrix-avg", Query: "q_matrix", MatrixAggregation: config.MatrixAggregationAvg, Labels: labels},
+					{Name: "string", Query: "q_string", Type: "display", Labels: labels},
+				},
+			},
+		},
+	}
+	api := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_scalar": &model.Scalar{Value: 3},
+		"q_matrix": model.Matrix{
+			&model.SampleStream{
+				Metric: model.Metric{"instance": "a"},
+				Values: []model.SamplePair{{Timestamp: 1000, Value: 2}, {Timestamp: 2000, Value: 6}, {Timestamp: 3000, Value: 4}},
+			},
+			// 没有采样点的序列被忽略
+			&model.SampleStream{Metric: model.Metric{"instance": "b"}},
+		},
+		"q_string": &model.String{Value: "v2.53.0"},
+	}}
+
+	reportData, err := NewCollector(api, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+	group := reportData.MetricGroups["group"]
+
+	scalar := group.MetricsByName["scalar"]
+	if len(scalar) != 1 || scalar[0].Value != 3 || scalar[0].Status != threshold.StatusCritical {
+		t.Errorf("标量应转换为一行数据并参与阈值判断: %+v", scalar)
+	}
+	// 没有标签的结果按配置的标签填充 "-"，与其他行的表头列一致
+	placeholder := []report.LabelData{{Name: "instance", Alias: "节点", Value: report.MissingLabelValue}}
+	if len(scalar) == 1 && !reflect.DeepEqual(scalar[0].Labels, placeholder) {
+		t.Errorf("标量的标签应为配置标签的占位值，实际 %+v", scalar[0].Labels)
+	}
+
+	for name, want := range map[string]float64{"matrix-last": 4, "matrix-max": 6, "matrix-avg": 4} {
+		rows := group.MetricsByName[name]
+		if len(rows) != 1 {
+			t.Errorf("%s 期望 1 行，实际 %d", name, len(rows))
+			continue
+		}
+		if rows[0].Value != want || rows[0].Labels[0].Value != "a" {
+			t.Errorf("%s 期望值 %v，实际 %+v", name, want, rows[0])
+		}
+	}
+
+	str := group.MetricsByName["string"]
+	if len(str) != 1 || !str[0].Text || str[0].Display != "v2.53.0" || str[0].Status != threshold.StatusNormal {
+		t.Errorf("字符串结果应作为展示值: %+v", str)
+	}
+	if len(str) == 1 && !reflect.DeepEqual(str[0].Labels, placeholder) {
+		t.Errorf("字符串结果的标签应为配置标签的占位值，实际 %+v", str[0].Labels)
+	}
+}
+
+func TestCollectorMissingLabelPolicy(t *testing.T) {
+	labels := config.Labels{{Name: "instance", Alias: "节点"}, {Name: "mountpoint", Alias: "挂载点"}}
+	metric := func(name, policy string) config.MetricConfig {
+		return config.MetricConfig{Name: name, Query: "q_disk", Labels: labels, MissingLabelPolicy: policy}
+	}
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					metric("default", ""),
+					metric("placeholder", config.MissingLabelPlaceholder),
+					metric("flag", config.MissingLabelFlag),
+				},
+			},
+		},
+	}
+	api := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_disk": model.Vector{
+			&model.Sample{Metric: model.Metric{"instance": "a", "mountpoint": "/"}, Value: 1},
+			&model.Sample{Metric: model.Metric{"instance": "b"}, Value: 2},
+		},
+	}}
+
+	reportData, err := NewCollector(api, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+	group := reportData.MetricGroups["group"]
+
+	if rows := group.MetricsByName["default"]; len(rows) != 1 {
+		t.Errorf("默认应丢弃缺少标签的序列，实际 %d 行", len(rows))
+	}
+	rows := group.MetricsByName["placeholder"]
+	if len(rows) != 2 || rows[1].Labels[1].Value != "-" || len(rows[1].MissingLabels) != 0 {
+		t.Errorf("placeholder 应保留序列并以 - 占位: %+v", rows)
+	}
+	rows = group.MetricsByName["flag"]
+	if len(rows) != 2 || len(rows[1].MissingLabels) != 1 || rows[1].MissingLabels[0] != "mountpoint" {
+		t.Errorf("flag 应保留序列并标出缺失的标签: %+v", rows)
+	}
+
+	quality := reportData.Quality
+	if quality.Total != 6 || quality.MissingLabels != 3 || quality.Dropped != 1 || len(quality.Metrics) != 3 {
+		t.Errorf("数据质量统计不正确: %+v", quality)
+	}
+	if first := quality.Metrics[0]; first.Metric != "default" || first.Policy != config.MissingLabelDrop || first.Dropped != 1 || first.Labels[0] != "mountpoint" {
+		t.Errorf("指标数据质量不正确: %+v", first)
+	}
+}
+
+func TestCollectorThresholdQueryHasOwnTimeout(t *testing.T) {
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					{
+						Name:           "slow",
+						Query:          "q_value",
+						ThresholdQuery: "q_threshold",
+						Threshold:      90,
+						ThresholdType:  "greater",
+						Timeout:        config.Duration(150 * time.Millisecond),
+						Labels:         config.Labels{{Name: "instance", Alias: "节点"}},
+					},
+				},
+			},
+		},
+	}
+	api := &MockPrometheusAPI{
+		responses: map[string]model.Value{
+			"q_value":     model.Vector{&model.Sample{Metric: model.Metric{"instance": "a"}, Value: 50}},
+			"q_threshold": model.Vector{&model.Sample{Metric: model.Metric{"instance": "a"}, Value: 40}},
+		},
+		// 两次查询合计超过单个查询超时，但各自都在超时之内
+		delays: map[string]time.Duration{"q_value": 100 * time.Millisecond, "q_threshold": 100 * time.Millisecond},
+	}
+
+	reportData, err := NewCollector(api, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+	rows := reportData.MetricGroups["group"].MetricsByName["slow"]
+	if len(rows) != 1 || rows[0].Threshold != 40 {
+		t.Errorf("threshold_query 应使用独立的超时并生效: %+v", rows)
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/report"
+)
+
+// resultSeries 返回查询结果中各序列的标签，标量和字符串结果视为一条不带标签的序列
+func resultSeries(result model.Value) []model.Metric {
+	switch v := result.(type) {
+	case model.Vector:
+		series := make([]model.Metric, 0, len(v))
+		for _, sample := range v {
+			series = append(series, sample.Metric)
+		}
+		return series
+	case model.Matrix:
+		series := make([]model.Metric, 0, len(v))
+		for _, stream := range v {
+			series = append(series, stream.Metric)
+		}
+		return series
+	case *model.Scalar, *model.String:
+		return []model.Metric{{}}
+	}
+	return nil
+}
+
+// missingSeries 按 expect 配置检查查询结果，返回缺失序列对应的报告行
+func (c *Collector) missingSeries(ctx context.Context, client PrometheusAPI, metric config.MetricConfig, result model.Value) []report.MetricData {
+	if metric.Expect == nil {
+		return nil
+	}
+	expect := metric.Expect.WithDefaults()
+	series := resultSeries(result)
+
+	var rows []report.MetricData
+	if expect.MinSeries > 0 && len(series) < expect.MinSeries {
+		log.Printf("警告: 指标 [%s] 查询到 %d 条序列，期望至少 %d 条", metric.Name, len(series), expect.MinSeries)
+		rows = append(rows, missingRow(metric, expect, "", "",
+			fmt.Sprintf("序列数 %d，期望至少 %d", len(series), expect.MinSeries),
+			fmt.Sprintf("至少 %d 条序列", expect.MinSeries)))
+	}
+
+	present := make(map[string]bool, len(series))
+	for _, m := range series {
+		if value := string(m[model.LabelName(expect.Label)]); value != "" {
+			present[value] = true
+		}
+	}
+	for _, value := range c.expectedValues(ctx, client, metric, expect) {
+		if present[value] {
+			continue
+		}
+		log.Printf("警告: 指标 [%s] 缺少 %s=%s 的序列", metric.Name, expect.Label, value)
+		display := "未查询到该序列"
+		if !metric.Labels.Has(expect.Label) {
+			// 标签不在表格列中时在值中说明缺失的是哪条序列
+			display = fmt.Sprintf("未查询到 %s=%s 的序列", expect.Label, value)
+		}
+		rows = append(rows, missingRow(metric, expect, expect.Label, value, display, "期望存在该序列"))
+	}
+	return rows
+}
+
+// expectedValues 返回期望存在的标签值：先按 expected_instances 的顺序，再追加 instances_query 结果中的值
+func (c *Collector) expectedValues(ctx context.Context, client PrometheusAPI, metric config.MetricConfig, expect config.ExpectConfig) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, value := range expect.ExpectedInstances {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	if expect.InstancesQuery == "" {
+		return values
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, c.queryTimeout(metric))
+	defer cancel()
+	result, _, err := client.Query(queryCtx, expect.InstancesQuery, time.Now())
+	if err != nil {
+		log.Printf("警告: 指标 [%s] 查询期望序列失败，只检查 expected_instances: %v, PromQL: %s", metric.Name, err, expect.InstancesQuery)
+		return values
+	}
+	var queried []string
+	for _, m := range resultSeries(result) {
+		value := string(m[model.LabelName(expect.Label)])
+		if value != "" && !seen[value] {
+			seen[value] = true
+			queried = append(queried, value)
+		}
+	}
+	sort.Strings(queried)
+	return append(values, queried...)
+}
+
+// missingRow 生成缺失序列的报告行，label 为空时所有标签列显示 "-"
+func missingRow(metric config.MetricConfig, expect config.ExpectConfig, label, value, display, thresholdText string) report.MetricData {
+	labels := placeholderLabels(metric)
+	for i := range labels {
+		if labels[i].Name == label {
+			labels[i].Value = value
+		}
+	}
+	return report.MetricData{
+		Name:          metric.Name,
+		Description:   metric.Description,
+		Unit:          metric.Unit,
+		Display:       display,
+		Missing:       true,
+		Status:        expect.AbsentSeverity,
+		StatusText:    report.GetStatusText(expect.AbsentSeverity),
+		Timestamp:     time.Now(),
+		Labels:        labels,
+		ThresholdText: thresholdText,
+		ThresholdRule: "expect",
+	}
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/threshold"
+)
+
+func TestCollectorExpectMissingSeries(t *testing.T) {
+	labels := config.Labels{{Name: "instance", Alias: "节点"}}
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					{
+						Name:   "instances",
+						Query:  "q_nodes",
+						Type:   "display",
+						Labels: labels,
+						Expect: &config.ExpectConfig{
+							ExpectedInstances: []string{"node1", "node3"},
+							InstancesQuery:    "q_up",
+						},
+					},
+					{
+						Name:   "min-series",
+						Query:  "q_empty",
+						Labels: labels,
+						Expect: &config.ExpectConfig{MinSeries: 1, AbsentSeverity: threshold.StatusWarning},
+					},
+					{
+						Name:   "other-label",
+						Query:  "q_nodes",
+						Labels: config.Labels{{Name: "job", Alias: "任务"}},
+						Expect: &config.ExpectConfig{Label: "instance", ExpectedInstances: []string{"node9"}},
+					},
+				},
+			},
+		},
+	}
+	api := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_nodes": model.Vector{
+			&model.Sample{Metric: model.Metric{"instance": "node1", "job": "node"}, Value: 1},
+			&model.Sample{Metric: model.Metric{"instance": "node2", "job": "node"}, Value: 1},
+		},
+		"q_up": model.Vector{
+			&model.Sample{Metric: model.Metric{"instance": "node4"}, Value: 1},
+			&model.Sample{Metric: model.Metric{"instance": "node2"}, Value: 1},
+		},
+		"q_empty": model.Vector{},
+	}}
+
+	reportData, err := NewCollector(api, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+	group := reportData.MetricGroups["group"]
+
+	rows := group.MetricsByName["instances"]
+	var missing []string
+	for _, row := range rows {
+		if !row.Missing {
+			continue
+		}
+		missing = append(missing, row.Labels[0].Value)
+		if row.Status != threshold.StatusCritical {
+			t.Errorf("缺失序列默认应为严重状态，即使是展示类指标: %+v", row)
+		}
+	}
+	if len(rows) != 4 || len(missing) != 2 || missing[0] != "node3" || missing[1] != "node4" {
+		t.Errorf("期望缺失 node3、node4，实际 %v（共 %d 行）", missing, len(rows))
+	}
+
+	rows = group.MetricsByName["min-series"]
+	if len(rows) != 1 || !rows[0].Missing || rows[0].Status != threshold.StatusWarning || rows[0].Labels[0].Value != "-" {
+		t.Errorf("序列数不足时应显示一行 absent_severity 状态的记录: %+v", rows)
+	}
+
+	rows = group.MetricsByName["other-label"]
+	if len(rows) != 3 || !rows[2].Missing || rows[2].Display != "未查询到 instance=node9 的序列" {
+		t.Errorf("检查的标签不在表格列中时应在值中说明缺失的序列: %+v", rows)
+	}
+}
+
+func TestCollectorScalarRowsShareLabelColumns(t *testing.T) {
+	labels := config.Labels{{Name: "instance", Alias: "节点"}, {Name: "job", Alias: "任务"}}
+	testConfig := &config.Config{
+		MetricTypes: []config.MetricType{
+			{
+				Type: "group",
+				Metrics: []config.MetricConfig{
+					{
+						Name:   "scalar",
+						Query:  "q_scalar",
+						Labels: labels,
+						Expect: &config.ExpectConfig{ExpectedInstances: []string{"node1"}},
+					},
+				},
+			},
+		},
+	}
+	api := &MockPrometheusAPI{responses: map[string]model.Value{
+		"q_scalar": &model.Scalar{Value: 1},
+	}}
+
+	reportData, err := NewCollector(api, testConfig).CollectMetrics()
+	if err != nil {
+		t.Fatalf("收集指标失败: %v", err)
+	}
+
+	// 报告表头取自第一行，标量行与缺失序列行的标签列必须一致
+	rows := reportData.MetricGroups["group"].MetricsByName["scalar"]
+	if len(rows) != 2 || rows[0].Missing || !rows[1].Missing {
+		t.Fatalf("期望一行标量数据和一行缺失序列，实际 %+v", rows)
+	}
+	for _, row := range rows {
+		if len(row.Labels) != len(labels) {
+			t.Errorf("每行都应包含全部配置标签，实际 %+v", row.Labels)
+			continue
+		}
+		for i, label := range row.Labels {
+			if label.Name != labels[i].Name || label.Alias != labels[i].Alias {
+				t.Errorf("标签列 %d 期望 %s，实际 %+v", i, labels[i].Name, label)
+			}
+		}
+	}
+	if rows[1].Labels[0].Value != "node1" || rows[0].Labels[0].Value != "-" {
+		t.Errorf("标量行标签应为 \"-\"，缺失行应标出 node1: %+v", rows)
+	}
+}
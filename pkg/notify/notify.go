@@ -19,6 +19,8 @@ import (
 	"path/filepath"
 	"time"
 
+	"PromAI/pkg/report"
+
 	"github.com/jordan-wright/email"
 )
 
@@ -27,6 +29,7 @@ type DingtalkConfig struct {
 	Webhook   string `yaml:"webhook"`
 	Secret    string `yaml:"secret"`
 	ReportURL string `yaml:"report_url"`
+	AttachPDF bool   `yaml:"attach_pdf"` // 消息中附带 PDF 报告链接（钉钉机器人不支持直接发送文件）
 }
 
 type EmailConfig struct {
@@ -38,6 +41,7 @@ type EmailConfig struct {
 	From      string   `yaml:"from"`
 	To        []string `yaml:"to"`
 	ReportURL string   `yaml:"report_url"`
+	AttachPDF bool     `yaml:"attach_pdf"` // 同时附加 PDF 报告
 }
 
 type WeComConfig struct {
@@ -45,6 +49,7 @@ type WeComConfig struct {
 	Webhook      string `yaml:"webhook"`
 	ReportURL    string `yaml:"report_url"`
 	ProjectTitle string `yaml:"project_title"`
+	AttachPDF    bool   `yaml:"attach_pdf"` // 额外发送 PDF 报告文件
 }
 
 // config/config.yaml 中 dingtalk 配置
@@ -96,6 +101,12 @@ func SendDingtalk(config DingtalkConfig, reportPath string) error {
 	reportFileName := filepath.Base(reportPath)
 	reportLink := fmt.Sprintf("%s/reports/%s", config.ReportURL, reportFileName)
 
+	// PDF 报告链接
+	pdfLine := ""
+	if pdfPath := pdfAttachment(config.AttachPDF, reportPath); pdfPath != "" {
+		pdfLine = fmt.Sprintf("- **PDF报告**：[点击下载](%s/reports/%s)\n", config.ReportURL, filepath.Base(pdfPath))
+	}
+
 	// 添加消息内容
 	messageContent := map[string]interface{}{
 		"msgtype": "markdown",
@@ -106,12 +117,14 @@ func SendDingtalk(config DingtalkConfig, reportPath string) error {
 				"> %s\n\n"+
 				"### 📄 报告详情\n"+
 				"- **文件名**：`%s`\n"+
-				"- **访问链接**：[点击查看报告](%s)\n\n"+
+				"- **访问链接**：[点击查看报告](%s)\n"+
+				"%s\n"+
 				"---\n"+
 				"💡 请登录环境查看完整报告内容",
 				time.Now().Format("2006-01-02 15:04:05"),
 				reportFileName,
-				reportLink),
+				reportLink,
+				pdfLine),
 		},
 	}
 
@@ -185,6 +198,12 @@ func SendEmail(config EmailConfig, reportPath string) error {
 		log.Printf("添加附件失败: %v", err)
 		return fmt.Errorf("添加附件失败: %v", err)
 	}
+	if pdfPath := pdfAttachment(config.AttachPDF, reportPath); pdfPath != "" {
+		if _, err := e.AttachFile(pdfPath); err != nil {
+			log.Printf("添加PDF附件失败: %v", err)
+			return fmt.Errorf("添加PDF附件失败: %v", err)
+		}
+	}
 
 	// 发送邮件（使用TLS）
 	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
@@ -249,16 +268,42 @@ func SendWeCom(config WeComConfig, reportPath string) error {
 		},
 	}
 
+	if err := postWeCom(config.Webhook, messageContent); err != nil {
+		return err
+	}
+
+	// 发送 PDF 报告文件：先上传素材，再发送文件消息
+	if pdfPath := pdfAttachment(config.AttachPDF, reportPath); pdfPath != "" {
+		mediaID, err := uploadWeComMedia(config.Webhook, pdfPath)
+		if err != nil {
+			log.Printf("上传企业微信文件失败: %v", err)
+			return fmt.Errorf("上传文件失败: %v", err)
+		}
+		fileMessage := map[string]interface{}{
+			"msgtype": "file",
+			"file":    map[string]string{"media_id": mediaID},
+		}
+		if err := postWeCom(config.Webhook, fileMessage); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("企业微信通知发送成功")
+	return nil
+}
+
+// postWeCom 向企业微信机器人发送消息
+func postWeCom(webhook string, messageContent map[string]interface{}) error {
 	jsonData, err := json.Marshal(messageContent)
 	if err != nil {
 		log.Printf("企业微信 JSON 编码失败: %v", err)
 		return fmt.Errorf("JSON编码失败: %v", err)
 	}
 
-	log.Printf("准备发送请求到企业微信 webhook: %s", config.Webhook)
+	log.Printf("准备发送请求到企业微信 webhook: %s", webhook)
 
 	// 发送 POST 请求
-	req, err := http.NewRequest("POST", config.Webhook, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", webhook, bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("创建企业微信请求失败: %v", err)
 		return fmt.Errorf("创建请求失败: %v", err)
@@ -279,7 +324,61 @@ func SendWeCom(config WeComConfig, reportPath string) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("企业微信发送失败，状态码: %d", resp.StatusCode)
 	}
-
-	log.Printf("企业微信通知发送成功")
 	return nil
 }
+
+// uploadWeComMedia 上传文件到企业微信机器人，返回 media_id
+func uploadWeComMedia(webhook, filePath string) (string, error) {
+	webhookURL, err := url.Parse(webhook)
+	if err != nil {
+		return "", fmt.Errorf("解析 webhook 失败: %v", err)
+	}
+	uploadURL := fmt.Sprintf("%s://%s/cgi-bin/webhook/upload_media?key=%s&type=file",
+		webhookURL.Scheme, webhookURL.Host, url.QueryEscape(webhookURL.Query().Get("key")))
+
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("media", filepath.Base(filePath))
+	if err != nil {
+		return "", fmt.Errorf("创建表单文件失败: %v", err)
+	}
+	part.Write(fileContent)
+	writer.Close()
+
+	resp, err := http.Post(uploadURL, writer.FormDataContentType(), body)
+	if err != nil {
+		return "", fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		MediaID string `json:"media_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.ErrCode != 0 || result.MediaID == "" {
+		return "", fmt.Errorf("企业微信返回错误: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return result.MediaID, nil
+}
+
+// pdfAttachment 返回报告对应的 PDF 路径，未开启或未生成 PDF 时返回空
+func pdfAttachment(enabled bool, reportPath string) string {
+	if !enabled {
+		return ""
+	}
+	pdfPath := report.PDFPath(reportPath)
+	if _, err := os.Stat(pdfPath); err != nil {
+		log.Printf("未找到 PDF 报告 %s，跳过附件", pdfPath)
+		return ""
+	}
+	return pdfPath
+}
@@ -7,21 +7,61 @@ import (
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 )
 
+// DefaultDatasource 由 prometheus_url 生成的默认数据源名称
+const DefaultDatasource = "default"
+
+// DatasourceConfig Prometheus/Thanos 数据源配置
+type DatasourceConfig struct {
+	Name string `yaml:"name"` // 数据源名称，供指标通过 datasource 引用
+	URL  string `yaml:"url"`  // Prometheus 或 Thanos Query 地址
+
+	// 认证与 TLS
+	BasicAuth       *BasicAuthConfig  `yaml:"basic_auth,omitempty"`
+	BearerToken     string            `yaml:"bearer_token,omitempty"`
+	BearerTokenFile string            `yaml:"bearer_token_file,omitempty"` // 每次请求前检查文件变化并重新加载
+	TLS             TLSConfig         `yaml:"tls_config,omitempty"`
+	Headers         map[string]string `yaml:"headers,omitempty"` // 附加的请求头，如 X-Scope-OrgID
+}
+
 // Client 封装 Prometheus 客户端
 type Client struct {
-	API v1.API
+	Name string
+	API  v1.API
 }
 
 // NewClient 创建新的 Prometheus 客户端
-func NewClient(url string) (*Client, error) {
+func NewClient(ds DatasourceConfig) (*Client, error) {
+	roundTripper, err := newRoundTripper(ds)
+	if err != nil {
+		return nil, fmt.Errorf("configuring prometheus transport: %w", err)
+	}
+
 	client, err := api.NewClient(api.Config{
-		Address: url,
+		Address:      ds.URL,
+		RoundTripper: roundTripper,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating prometheus client: %w", err)
 	}
 
 	return &Client{
-		API: v1.NewAPI(client),
+		Name: ds.Name,
+		API:  v1.NewAPI(client),
 	}, nil
 }
+
+// NewClients 按数据源配置创建客户端，返回以数据源名称为键的映射
+func NewClients(datasources []DatasourceConfig) (map[string]*Client, error) {
+	clients := make(map[string]*Client, len(datasources))
+	for _, ds := range datasources {
+		if _, exists := clients[ds.Name]; exists {
+			return nil, fmt.Errorf("duplicate datasource %q", ds.Name)
+		}
+		client, err := NewClient(ds)
+		if err != nil {
+			return nil, fmt.Errorf("datasource %q: %w", ds.Name, err)
+		}
+		clients[ds.Name] = client
+	}
+	return clients, nil
+}
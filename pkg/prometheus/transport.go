@@ -0,0 +1,186 @@
+package prometheus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+)
+
+// BasicAuthConfig 基础认证配置
+type BasicAuthConfig struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"` // 从文件读取密码，文件变化后自动重新加载
+}
+
+// TLSConfig TLS 配置，同时配置 cert_file 和 key_file 时启用 mTLS
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// newRoundTripper 根据数据源的认证、TLS 和请求头配置创建 RoundTripper
+func newRoundTripper(ds DatasourceConfig) (http.RoundTripper, error) {
+	if ds.BearerToken != "" && ds.BearerTokenFile != "" {
+		return nil, fmt.Errorf("bearer_token and bearer_token_file are mutually exclusive")
+	}
+	if ds.BasicAuth != nil && (ds.BearerToken != "" || ds.BearerTokenFile != "") {
+		return nil, fmt.Errorf("basic_auth and bearer token are mutually exclusive")
+	}
+	if ds.BasicAuth != nil && ds.BasicAuth.Password != "" && ds.BasicAuth.PasswordFile != "" {
+		return nil, fmt.Errorf("basic_auth password and password_file are mutually exclusive")
+	}
+
+	tlsConfig, err := newTLSConfig(ds.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := api.DefaultRoundTripper.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	rt := &authRoundTripper{
+		next:    transport,
+		headers: ds.Headers,
+	}
+	switch {
+	case ds.BearerToken != "":
+		rt.bearerToken = staticSecret(ds.BearerToken)
+	case ds.BearerTokenFile != "":
+		rt.bearerToken = &fileSecret{path: ds.BearerTokenFile}
+	}
+	if ds.BasicAuth != nil {
+		rt.username = ds.BasicAuth.Username
+		if ds.BasicAuth.PasswordFile != "" {
+			rt.password = &fileSecret{path: ds.BasicAuth.PasswordFile}
+		} else {
+			rt.password = staticSecret(ds.BasicAuth.Password)
+		}
+	}
+	return rt, nil
+}
+
+// newTLSConfig 加载 CA 证书和客户端证书
+func newTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be configured together")
+	}
+	if cfg.CertFile != "" {
+		// 每次握手时重新读取证书，便于证书轮换
+		if _, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile); err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// secret 认证凭据来源
+type secret interface {
+	Value() (string, error)
+}
+
+type staticSecret string
+
+func (s staticSecret) Value() (string, error) {
+	return string(s), nil
+}
+
+// fileSecret 从文件读取凭据，文件修改后重新加载（如 Kubernetes ServiceAccount Token）
+type fileSecret struct {
+	path string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+}
+
+func (s *fileSecret) Value() (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.value != "" && info.ModTime().Equal(s.modTime) {
+		return s.value, nil
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials file: %w", err)
+	}
+	s.value = strings.TrimSpace(string(content))
+	s.modTime = info.ModTime()
+	return s.value, nil
+}
+
+// authRoundTripper 为请求附加认证信息和自定义请求头
+type authRoundTripper struct {
+	next        http.RoundTripper
+	headers     map[string]string
+	bearerToken secret
+	username    string
+	password    secret
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	for name, value := range rt.headers {
+		req.Header.Set(name, value)
+	}
+
+	if rt.bearerToken != nil {
+		token, err := rt.bearerToken.Value()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if rt.password != nil {
+		password, err := rt.password.Value()
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(rt.username, password)
+	}
+
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// captureRequest 发送请求并返回服务端收到的请求头
+func captureRequest(t *testing.T, rt http.RoundTripper, url string) http.Header {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("创建请求失败: %v", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	return http.Header{
+		"Authorization": {resp.Header.Get("X-Got-Authorization")},
+		"X-Scope-Orgid": {resp.Header.Get("X-Got-Scope-OrgID")},
+	}
+}
+
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Got-Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("X-Got-Scope-OrgID", r.Header.Get("X-Scope-OrgID"))
+	}))
+}
+
+func TestRoundTripperBearerTokenFileReload(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := newRoundTripper(DatasourceConfig{
+		URL:             server.URL,
+		BearerTokenFile: tokenFile,
+		Headers:         map[string]string{"X-Scope-OrgID": "tenant-a"},
+	})
+	if err != nil {
+		t.Fatalf("创建 RoundTripper 失败: %v", err)
+	}
+
+	got := captureRequest(t, rt, server.URL)
+	if got.Get("Authorization") != "Bearer first-token" {
+		t.Errorf("Authorization 不正确: %q", got.Get("Authorization"))
+	}
+	if got.Get("X-Scope-Orgid") != "tenant-a" {
+		t.Errorf("X-Scope-OrgID 不正确: %q", got.Get("X-Scope-Orgid"))
+	}
+
+	// 模拟 Token 轮换
+	if err := os.WriteFile(tokenFile, []byte("second-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(tokenFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	got = captureRequest(t, rt, server.URL)
+	if got.Get("Authorization") != "Bearer second-token" {
+		t.Errorf("Token 文件更新后应重新加载，实际: %q", got.Get("Authorization"))
+	}
+}
+
+func TestRoundTripperBasicAuth(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	rt, err := newRoundTripper(DatasourceConfig{
+		URL:       server.URL,
+		BasicAuth: &BasicAuthConfig{Username: "admin", Password: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("创建 RoundTripper 失败: %v", err)
+	}
+
+	got := captureRequest(t, rt, server.URL)
+	if got.Get("Authorization") != "Basic YWRtaW46c2VjcmV0" {
+		t.Errorf("Basic Auth 不正确: %q", got.Get("Authorization"))
+	}
+}
+
+func TestRoundTripperRejectsConflictingAuth(t *testing.T) {
+	_, err := newRoundTripper(DatasourceConfig{
+		BearerToken: "token",
+		BasicAuth:   &BasicAuthConfig{Username: "admin", Password: "secret"},
+	})
+	if err == nil {
+		t.Error("同时配置 basic_auth 和 bearer_token 应该返回错误")
+	}
+}
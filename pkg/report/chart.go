@@ -0,0 +1,204 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Chart 报告中的一张图表，Labels 与每个数据集的 Values 一一对应
+type Chart struct {
+	ID     string
+	Title  string
+	Unit   string
+	Labels []string
+	Series []ChartSeries
+}
+
+// ChartSeries 图表中的一个数据集
+type ChartSeries struct {
+	Name   string
+	Values []*float64 // 缺失的采样点为 null，折线在此断开
+}
+
+// chartLabel 返回一行数据在图表中的标签，按标签名排序后格式化，保证与标签切片的顺序无关
+func chartLabel(labels []LabelData) string {
+	sorted := make([]LabelData, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return formatLabels(sorted)
+}
+
+// hasLabelValue 判断是否至少有一个标签有实际值，标量等结果的标签全部为 "-"，图表中以指标名代替
+func hasLabelValue(labels []LabelData) bool {
+	for _, label := range labels {
+		if label.Value != "" && label.Value != MissingLabelValue {
+			return true
+		}
+	}
+	return false
+}
+
+// metricChartKey 返回指标图表在 ReportData.ChartData 中的键
+func metricChartKey(groupType, metricName string) string {
+	return fmt.Sprintf("%s_%s", groupType, metricName)
+}
+
+// buildMetricChart 为单个指标生成图表：每行数据对应一个标签，按标签排序，数值与标签一一对应
+func buildMetricChart(key, metricName string, metrics []MetricData, withDatasource bool) *Chart {
+	type point struct {
+		label string
+		value float64
+	}
+	points := make([]point, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.Missing {
+			continue
+		}
+		label := chartLabel(metric.Labels)
+		if !hasLabelValue(metric.Labels) {
+			label = metricName
+		}
+		// 多个数据源可能存在相同标签的序列
+		if withDatasource && metric.Datasource != "" {
+			label = metric.Datasource + "/" + label
+		}
+		points = append(points, point{label: label, value: metric.Value})
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].label < points[j].label })
+
+	chart := &Chart{
+		ID:     key,
+		Title:  metricName,
+		Labels: make([]string, len(points)),
+		Series: []ChartSeries{{Name: metricName, Values: make([]*float64, len(points))}},
+	}
+	if len(metrics) > 0 {
+		chart.Unit = metrics[0].Unit
+	}
+	for i, p := range points {
+		value := p.value
+		chart.Labels[i] = p.label
+		chart.Series[0].Values[i] = &value
+	}
+	return chart
+}
+
+// buildMetricCharts 为每个有数据的指标生成图表，包括未在表格中展示的指标。
+// 图表 ID 按键排序后编号，用作模板中 canvas 的 id
+func buildMetricCharts(data *ReportData) map[string]*Chart {
+	charts := make(map[string]*Chart)
+	withDatasource := len(data.Datasources) > 1
+	for _, group := range data.MetricGroups {
+		for metricName, metrics := range group.MetricsByName {
+			// 字符串结果没有数值，不生成图表
+			if len(metrics) == 0 || metrics[0].Text {
+				continue
+			}
+			key := metricChartKey(group.Type, metricName)
+			// 全部序列缺失时没有可绘制的数值
+			if chart := buildMetricChart(key, metricName, metrics, withDatasource); len(chart.Labels) > 0 {
+				charts[key] = chart
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(charts))
+	for key := range charts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		charts[key].ID = fmt.Sprintf("metric-chart-%d", i)
+	}
+	return charts
+}
+
+// resourceChartRoles 资源使用概览中的数据集，按显示顺序
+var resourceChartRoles = []struct {
+	role string
+	name string
+}{
+	{RoleCPUUsage, "CPU使用率"},
+	{RoleMemUsage, "内存使用率"},
+	{RoleDiskUsage, "磁盘使用率"},
+}
+
+// isRootMountpoint 资源使用概览只展示根分区的磁盘使用率
+func isRootMountpoint(mountpoint string) bool {
+	return mountpoint == "/" || mountpoint == "rootfs"
+}
+
+// buildResourceChart 按主机生成资源使用概览：每台主机一个标签，CPU、内存、根分区磁盘使用率按主机对齐，
+// 主机缺少某项数据时该位置为 null
+func buildResourceChart(data *ReportData) *Chart {
+	hostLabels := data.HostLabels.WithDefaults()
+	withDatasource := len(data.Datasources) > 1
+
+	type hostValues struct {
+		label  string
+		values []*float64
+	}
+	hosts := make(map[string]*hostValues)
+	for _, group := range data.MetricGroups {
+		for _, metrics := range group.MetricsByName {
+			for _, m := range metrics {
+				if m.Missing || m.Text {
+					continue
+				}
+				index := -1
+				for i, r := range resourceChartRoles {
+					if r.role == m.Role {
+						index = i
+					}
+				}
+				if index < 0 {
+					continue
+				}
+				if m.Role == RoleDiskUsage && !isRootMountpoint(labelValue(m.Labels, hostLabels.MountpointLabel)) {
+					continue
+				}
+				instance := labelValue(m.Labels, hostLabels.HostLabel)
+				if instance == "" {
+					continue
+				}
+
+				key := hostKey(m.Datasource, instance)
+				host, exists := hosts[key]
+				if !exists {
+					label := instance
+					if withDatasource {
+						label = m.Datasource + "/" + instance
+					}
+					host = &hostValues{label: label, values: make([]*float64, len(resourceChartRoles))}
+					hosts[key] = host
+				}
+				value := m.Value
+				host.values[index] = &value
+			}
+		}
+	}
+
+	sorted := make([]*hostValues, 0, len(hosts))
+	for _, host := range hosts {
+		sorted = append(sorted, host)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].label < sorted[j].label })
+
+	chart := &Chart{
+		ID:     "resourceChart",
+		Title:  "资源使用情况",
+		Unit:   "%",
+		Labels: make([]string, len(sorted)),
+		Series: make([]ChartSeries, len(resourceChartRoles)),
+	}
+	for i, r := range resourceChartRoles {
+		chart.Series[i] = ChartSeries{Name: r.name, Values: make([]*float64, len(sorted))}
+	}
+	for i, host := range sorted {
+		chart.Labels[i] = host.label
+		for j, value := range host.values {
+			chart.Series[j].Values[i] = value
+		}
+	}
+	return chart
+}
@@ -0,0 +1,212 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMetricChartsDeterministic(t *testing.T) {
+	row := func(value float64, labels ...LabelData) MetricData {
+		return MetricData{Name: "磁盘使用率", Unit: "%", Value: value, Labels: labels}
+	}
+	instance := func(v string) LabelData { return LabelData{Name: "instance", Alias: "节点", Value: v} }
+	mountpoint := func(v string) LabelData { return LabelData{Name: "mountpoint", Alias: "挂载点", Value: v} }
+
+	// 行顺序和行内标签顺序均不固定
+	data := &ReportData{
+		MetricGroups: map[string]*MetricGroup{
+			"host": {
+				Type: "host",
+				MetricsByName: map[string][]MetricData{
+					"磁盘使用率": {
+						row(30, mountpoint("/data"), instance("b")),
+						row(10, instance("a"), mountpoint("/")),
+						row(40, instance("b"), mountpoint("/")),
+						row(20, mountpoint("/data"), instance("a")),
+					},
+					"CPU使用率": {
+						{Name: "CPU使用率", Value: 5, Labels: []LabelData{instance("z")}},
+						{Name: "CPU使用率", Value: 7, Labels: []LabelData{instance("y")}},
+						// 缺失的序列没有数值，不出现在图表中
+						{Name: "CPU使用率", Missing: true, Labels: []LabelData{instance("x")}},
+					},
+					"空指标":  {},
+					"缺失指标": {{Name: "缺失指标", Missing: true}},
+					"版本":   {{Name: "版本", Text: true, Display: "v2.53.0"}},
+				},
+			},
+		},
+	}
+
+	charts := buildMetricCharts(data)
+	if len(charts) != 2 {
+		t.Fatalf("期望2张图表，实际%d张", len(charts))
+	}
+
+	disk := charts["host_磁盘使用率"]
+	wantLabels := []string{
+		"节点=a, 挂载点=/",
+		"节点=a, 挂载点=/data",
+		"节点=b, 挂载点=/",
+		"节点=b, 挂载点=/data",
+	}
+	if !reflect.DeepEqual(disk.Labels, wantLabels) {
+		t.Errorf("标签顺序不正确: %v", disk.Labels)
+	}
+	wantValues := []float64{10, 20, 40, 30}
+	for i, want := range wantValues {
+		if got := disk.Series[0].Values[i]; got == nil || *got != want {
+			t.Errorf("%s 的值应为 %v，实际 %v", disk.Labels[i], want, got)
+		}
+	}
+	if disk.Unit != "%" || disk.Series[0].Name != "磁盘使用率" {
+		t.Errorf("图表信息不正确: %+v", disk)
+	}
+
+	// 每张图表只包含自己的标签
+	cpu := charts["host_CPU使用率"]
+	if !reflect.DeepEqual(cpu.Labels, []string{"节点=y", "节点=z"}) || *cpu.Series[0].Values[0] != 7 {
+		t.Errorf("CPU 图表不正确: %v %v", cpu.Labels, cpu.Series[0].Values)
+	}
+
+	// 多次生成结果一致
+	for i := 0; i < 20; i++ {
+		if again := buildMetricCharts(data); !reflect.DeepEqual(again, charts) {
+			t.Fatal("多次生成的图表不一致")
+		}
+	}
+}
+
+func TestBuildResourceChartAlignsByHost(t *testing.T) {
+	row := func(role, instance, mountpoint string, value float64) MetricData {
+		labels := []LabelData{{Name: "instance", Alias: "节点", Value: instance}}
+		if mountpoint != "" {
+			labels = append(labels, LabelData{Name: "mountpoint", Alias: "挂载点", Value: mountpoint})
+		}
+		return MetricData{Role: role, Value: value, Labels: labels}
+	}
+
+	// 各指标的行顺序不同，b 没有内存数据，磁盘有多个挂载点
+	data := &ReportData{
+		HostLabels: HostSummaryConfig{}.WithDefaults(),
+		MetricGroups: map[string]*MetricGroup{
+			"host": {
+				Type: "host",
+				MetricsByName: map[string][]MetricData{
+					"CPU使用率": {row(RoleCPUUsage, "b", "", 20), row(RoleCPUUsage, "a", "", 10)},
+					"内存使用率":  {row(RoleMemUsage, "c", "", 70), row(RoleMemUsage, "a", "", 50)},
+					"磁盘使用率": {
+						row(RoleDiskUsage, "b", "/data", 99),
+						row(RoleDiskUsage, "b", "/", 40),
+						row(RoleDiskUsage, "a", "/boot", 98),
+						row(RoleDiskUsage, "a", "/", 30),
+					},
+				},
+			},
+		},
+	}
+
+	chart := buildResourceChart(data)
+	if !reflect.DeepEqual(chart.Labels, []string{"a", "b", "c"}) {
+		t.Fatalf("主机标签不正确: %v", chart.Labels)
+	}
+	want := map[string][]interface{}{
+		"CPU使用率": {10.0, 20.0, nil},
+		"内存使用率":  {50.0, nil, 70.0},
+		"磁盘使用率":  {30.0, 40.0, nil},
+	}
+	for _, series := range chart.Series {
+		for i, value := range series.Values {
+			expected := want[series.Name][i]
+			if expected == nil {
+				if value != nil {
+					t.Errorf("%s 在 %s 上应为空，实际 %v", series.Name, chart.Labels[i], *value)
+				}
+				continue
+			}
+			if value == nil || *value != expected.(float64) {
+				t.Errorf("%s 在 %s 上应为 %v，实际 %v", series.Name, chart.Labels[i], expected, value)
+			}
+		}
+	}
+}
+
+func TestRenderReportCharts(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模板路径相对于项目根目录
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cpu := func(instance string, value float64) MetricData {
+		return MetricData{Name: "CPU使用率", Role: RoleCPUUsage, Unit: "%", Value: value, Status: "normal",
+			Labels: []LabelData{{Name: "instance", Alias: "节点", Value: instance}}}
+	}
+	mem := func(instance string, value float64) MetricData {
+		return MetricData{Name: "内存使用率", Role: RoleMemUsage, Unit: "%", Value: value, Status: "normal",
+			Labels: []LabelData{{Name: "instance", Alias: "节点", Value: instance}}}
+	}
+	data := ReportData{
+		Timestamp:  time.Now(),
+		Project:    "测试项目",
+		GroupOrder: []string{"host"},
+		HostLabels: HostSummaryConfig{}.WithDefaults(),
+		MetricGroups: map[string]*MetricGroup{
+			"host": {
+				Type:        "host",
+				MetricOrder: []string{"CPU使用率", "内存使用率"},
+				MetricsByName: map[string][]MetricData{
+					"CPU使用率": {cpu("b", 20), cpu("a", 10)},
+					"内存使用率":  {mem("a", 50), mem("b", 60)},
+				},
+			},
+		},
+	}
+	prepareReport(&data)
+
+	filename := filepath.Join(t.TempDir(), "report.html")
+	if err := renderReport(data, filename); err != nil {
+		t.Fatalf("渲染报告失败: %v", err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(content)
+
+	// 资源使用概览直接使用后端对齐的数据
+	const prefix = "const resourceChart = "
+	start := strings.Index(html, prefix)
+	if start < 0 {
+		t.Fatal("报告中没有资源使用概览数据")
+	}
+	// 只解码赋值语句中的 JSON 字面量，不依赖模板的换行符
+	var chart Chart
+	if err := json.NewDecoder(strings.NewReader(html[start+len(prefix):])).Decode(&chart); err != nil {
+		t.Fatalf("解析资源使用概览数据失败: %v", err)
+	}
+	if !reflect.DeepEqual(chart.Labels, []string{"a", "b"}) ||
+		*chart.Series[0].Values[0] != 10 || *chart.Series[0].Values[1] != 20 ||
+		*chart.Series[1].Values[0] != 50 || *chart.Series[1].Values[1] != 60 {
+		t.Errorf("渲染的资源使用概览未按主机对齐: %+v", chart)
+	}
+
+	// 每个指标的图表都有对应的 canvas
+	for _, key := range []string{"host_CPU使用率", "host_内存使用率"} {
+		if id := data.ChartData[key].ID; !strings.Contains(html, `<canvas id="`+id+`">`) {
+			t.Errorf("缺少指标图表 %s 的 canvas", key)
+		}
+	}
+	if strings.Contains(html, "getMetricValues") {
+		t.Error("不应再在前端按行顺序拼接图表数据")
+	}
+}
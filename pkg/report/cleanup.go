@@ -7,7 +7,8 @@ import (
     "time"
 )
 
-// CleanupReports 清理旧报告
+// CleanupReports 清理 reports 目录下的旧报告。子目录（如快照目录 reports/history）和
+// 历史快照不清理，报告对比、重新渲染和报告 API 依赖这些快照
 func CleanupReports(maxAge int) error {
     reportsDir := "reports"
     now := time.Now()
@@ -23,6 +24,16 @@ func CleanupReports(maxAge int) error {
             return nil
         }
 
+        // 不进入子目录，也不删除目录
+        if info.IsDir() {
+            return filepath.SkipDir
+        }
+
+        // 保留历史快照，包括 report_store.dir 配置为 reports 时保存的快照
+        if isSnapshotFile(info.Name()) {
+            return nil
+        }
+
         // 检查文件年龄
         if info.ModTime().Add(time.Duration(maxAge) * 24 * time.Hour).Before(now) {
             if err := os.Remove(path); err != nil {
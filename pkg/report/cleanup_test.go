@@ -0,0 +1,53 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupReportsKeepsSnapshots(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	old := time.Now().Add(-72 * time.Hour)
+	files := []string{
+		"reports/inspection_report_20240101_093000.html",
+		"reports/inspection_report_20240101_093000.json",
+		"reports/history/inspection_report_20240101_093000.json",
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(DefaultStoreDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CleanupReports(1); err != nil {
+		t.Fatalf("清理报告失败: %v", err)
+	}
+
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("过期的 HTML 报告应被删除: %v", err)
+	}
+	for _, file := range files[1:] {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("快照 %s 不应被清理: %v", file, err)
+		}
+	}
+}
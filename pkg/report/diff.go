@@ -0,0 +1,240 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultChangePercent 默认的数值变化幅度阈值（百分比）
+const defaultChangePercent = 20
+
+// ComparisonConfig 与上一次巡检结果对比的配置
+type ComparisonConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	ChangePercent float64 `yaml:"change_percent"` // 数值变化超过该百分比时列出，默认 20
+}
+
+// WithDefaults 返回填充默认值后的配置
+func (c ComparisonConfig) WithDefaults() ComparisonConfig {
+	if c.ChangePercent <= 0 {
+		c.ChangePercent = defaultChangePercent
+	}
+	return c
+}
+
+// DiffItem 对比结果中的一行，以指标名和标签集合区分
+type DiffItem struct {
+	Group          string
+	Metric         string
+	Datasource     string
+	Labels         []LabelData
+	PreviousStatus string // 上次状态，新出现的指标为空
+	Status         string // 本次状态，本次未出现的指标为空
+	PreviousValue  float64
+	Value          float64
+	PreviousText   string // 上次的显示值
+	Display        string // 本次的显示值
+	ChangePercent  float64
+}
+
+// ReportDiff 本次巡检与上一次巡检的差异
+type ReportDiff struct {
+	PreviousID        string
+	PreviousTimestamp time.Time
+	ChangePercent     float64
+	NewAlerts         []DiffItem // 新出现或升级的严重/警告项
+	Resolved          []DiffItem // 已恢复正常的严重/警告项
+	Vanished          []DiffItem // 上次为严重/警告、本次未出现的项
+	Changed           []DiffItem // 数值变化超过阈值的指标
+	HostsAdded        []string   // 新出现的主机
+	HostsRemoved      []string   // 消失的主机
+}
+
+// HasChanges 是否存在任何差异
+func (d *ReportDiff) HasChanges() bool {
+	return len(d.NewAlerts) > 0 || len(d.Resolved) > 0 || len(d.Vanished) > 0 || len(d.Changed) > 0 ||
+		len(d.HostsAdded) > 0 || len(d.HostsRemoved) > 0
+}
+
+// statusRank 状态的严重程度，用于判断是否升级
+func statusRank(status string) int {
+	switch status {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// metricKey 以指标名、数据源和排序后的标签集合作为行的唯一键
+func metricKey(m MetricData) string {
+	pairs := make([]string, 0, len(m.Labels))
+	for _, label := range m.Labels {
+		pairs = append(pairs, label.Name+"="+label.Value)
+	}
+	sort.Strings(pairs)
+	return m.Name + "|" + m.Datasource + "|" + strings.Join(pairs, ",")
+}
+
+// diffRow 带分组信息的指标行
+type diffRow struct {
+	group  string
+	metric MetricData
+}
+
+// orderedRows 按配置的分组和指标顺序展开所有指标行。show_in_table 为 false 的指标（如主机资源指标）
+// 同样参与对比，排在表格指标之后，按指标名排序
+func orderedRows(data *ReportData) []diffRow {
+	var rows []diffRow
+	for _, groupType := range data.GroupOrder {
+		group, exists := data.MetricGroups[groupType]
+		if !exists {
+			continue
+		}
+		shown := make(map[string]bool, len(group.MetricOrder))
+		names := make([]string, 0, len(group.MetricsByName))
+		for _, metricName := range group.MetricOrder {
+			shown[metricName] = true
+			names = append(names, metricName)
+		}
+		var hidden []string
+		for metricName := range group.MetricsByName {
+			if !shown[metricName] {
+				hidden = append(hidden, metricName)
+			}
+		}
+		sort.Strings(hidden)
+
+		for _, metricName := range append(names, hidden...) {
+			for _, m := range group.MetricsByName[metricName] {
+				rows = append(rows, diffRow{group: groupType, metric: m})
+			}
+		}
+	}
+	return rows
+}
+
+// hostSet 按主机标签收集报告中出现的主机，键与主机资源概览一致，值为显示名称。
+// 多数据源时显示名称带上数据源
+func hostSet(data *ReportData) map[string]string {
+	hostLabel := data.HostLabels.WithDefaults().HostLabel
+	hosts := make(map[string]string)
+	for _, row := range orderedRows(data) {
+		// 缺失的序列表示主机期望存在但未查询到，与主机资源概览一致不计入
+		if row.metric.Missing {
+			continue
+		}
+		instance := labelValue(row.metric.Labels, hostLabel)
+		if instance == "" {
+			continue
+		}
+		name := instance
+		if len(data.Datasources) > 1 {
+			name = row.metric.Datasource + "/" + instance
+		}
+		hosts[hostKey(row.metric.Datasource, instance)] = name
+	}
+	return hosts
+}
+
+// hasNumericValue 行是否有可比较的数值，缺失的序列和字符串结果没有
+func hasNumericValue(m MetricData) bool {
+	return !m.Missing && !m.Text
+}
+
+func displayValue(m MetricData) string {
+	if m.Display != "" {
+		return m.Display
+	}
+	return fmt.Sprintf("%.2f%s", m.Value, m.Unit)
+}
+
+// CompareReports 对比上一次与本次的巡检结果
+func CompareReports(previous *Snapshot, current *ReportData, cfg ComparisonConfig) *ReportDiff {
+	cfg = cfg.WithDefaults()
+	diff := &ReportDiff{
+		PreviousID:        previous.ID,
+		PreviousTimestamp: previous.Timestamp,
+		ChangePercent:     cfg.ChangePercent,
+	}
+
+	previousRows := make(map[string]MetricData)
+	for _, row := range orderedRows(&previous.Data) {
+		previousRows[metricKey(row.metric)] = row.metric
+	}
+
+	currentRows := make(map[string]bool)
+	for _, row := range orderedRows(current) {
+		m := row.metric
+		currentRows[metricKey(m)] = true
+		item := DiffItem{
+			Group:      row.group,
+			Metric:     m.Name,
+			Datasource: m.Datasource,
+			Labels:     m.Labels,
+			Status:     m.Status,
+			Value:      m.Value,
+			Display:    displayValue(m),
+		}
+
+		prev, existed := previousRows[metricKey(m)]
+		if existed {
+			item.PreviousStatus = prev.Status
+			item.PreviousValue = prev.Value
+			item.PreviousText = displayValue(prev)
+		}
+
+		switch {
+		case statusRank(m.Status) > statusRank(prev.Status):
+			// 新出现的告警，或由警告升级为严重
+			diff.NewAlerts = append(diff.NewAlerts, item)
+		case existed && statusRank(prev.Status) > 0 && statusRank(m.Status) == 0:
+			diff.Resolved = append(diff.Resolved, item)
+		case existed && hasNumericValue(m) && hasNumericValue(prev) && prev.Value != 0:
+			item.ChangePercent = (m.Value - prev.Value) / math.Abs(prev.Value) * 100
+			if math.Abs(item.ChangePercent) >= cfg.ChangePercent {
+				diff.Changed = append(diff.Changed, item)
+			}
+		}
+	}
+
+	// 上次的告警行本次未出现，可能是主机下线或查询不再返回该序列
+	for _, row := range orderedRows(&previous.Data) {
+		prev := row.metric
+		if statusRank(prev.Status) == 0 || currentRows[metricKey(prev)] {
+			continue
+		}
+		currentRows[metricKey(prev)] = true
+		diff.Vanished = append(diff.Vanished, DiffItem{
+			Group:          row.group,
+			Metric:         prev.Name,
+			Datasource:     prev.Datasource,
+			Labels:         prev.Labels,
+			PreviousStatus: prev.Status,
+			PreviousValue:  prev.Value,
+			PreviousText:   displayValue(prev),
+		})
+	}
+
+	previousHosts := hostSet(&previous.Data)
+	currentHosts := hostSet(current)
+	for key, name := range currentHosts {
+		if _, exists := previousHosts[key]; !exists {
+			diff.HostsAdded = append(diff.HostsAdded, name)
+		}
+	}
+	for key, name := range previousHosts {
+		if _, exists := currentHosts[key]; !exists {
+			diff.HostsRemoved = append(diff.HostsRemoved, name)
+		}
+	}
+	sort.Strings(diff.HostsAdded)
+	sort.Strings(diff.HostsRemoved)
+
+	return diff
+}
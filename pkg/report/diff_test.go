@@ -0,0 +1,184 @@
+package report
+
+import "testing"
+
+func diffTestData(rows ...MetricData) ReportData {
+	group := &MetricGroup{
+		Type:          "基础资源使用情况",
+		MetricsByName: make(map[string][]MetricData),
+	}
+	for _, row := range rows {
+		if _, exists := group.MetricsByName[row.Name]; !exists {
+			group.MetricOrder = append(group.MetricOrder, row.Name)
+		}
+		group.MetricsByName[row.Name] = append(group.MetricsByName[row.Name], row)
+	}
+	return ReportData{
+		Project:      "测试项目",
+		GroupOrder:   []string{group.Type},
+		MetricGroups: map[string]*MetricGroup{group.Type: group},
+	}
+}
+
+func cpuRow(instance string, value float64, status string) MetricData {
+	return MetricData{
+		Name:   "CPU使用率",
+		Value:  value,
+		Unit:   "%",
+		Status: status,
+		Labels: []LabelData{{Name: "instance", Alias: "实例", Value: instance}},
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	previous := &Snapshot{
+		ID: "20240101_080000",
+		Data: diffTestData(
+			cpuRow("a:9100", 50, "normal"),
+			cpuRow("b:9100", 95, "critical"),
+			cpuRow("c:9100", 40, "normal"),
+			cpuRow("d:9100", 10, "normal"),
+		),
+	}
+	current := diffTestData(
+		cpuRow("a:9100", 92, "critical"), // 新增告警
+		cpuRow("b:9100", 30, "normal"),   // 已恢复
+		cpuRow("c:9100", 60, "normal"),   // 变化 +50%
+		cpuRow("e:9100", 85, "warning"),  // 新主机上的告警
+	)
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+
+	if diff.ChangePercent != defaultChangePercent {
+		t.Errorf("默认变化阈值应为 %d，实际 %v", defaultChangePercent, diff.ChangePercent)
+	}
+	if len(diff.NewAlerts) != 2 || diff.NewAlerts[0].Labels[0].Value != "a:9100" || diff.NewAlerts[1].PreviousStatus != "" {
+		t.Errorf("新增告警不正确: %+v", diff.NewAlerts)
+	}
+	if len(diff.Resolved) != 1 || diff.Resolved[0].Labels[0].Value != "b:9100" {
+		t.Errorf("已恢复项不正确: %+v", diff.Resolved)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].ChangePercent != 50 {
+		t.Errorf("数值变化项不正确: %+v", diff.Changed)
+	}
+	if len(diff.HostsAdded) != 1 || diff.HostsAdded[0] != "e:9100" {
+		t.Errorf("新增主机不正确: %v", diff.HostsAdded)
+	}
+	if len(diff.HostsRemoved) != 1 || diff.HostsRemoved[0] != "d:9100" {
+		t.Errorf("消失主机不正确: %v", diff.HostsRemoved)
+	}
+}
+
+func TestMetricKeyIgnoresLabelOrder(t *testing.T) {
+	a := MetricData{Name: "磁盘使用率", Labels: []LabelData{{Name: "instance", Value: "a"}, {Name: "mountpoint", Value: "/"}}}
+	b := MetricData{Name: "磁盘使用率", Labels: []LabelData{{Name: "mountpoint", Value: "/"}, {Name: "instance", Value: "a"}}}
+	if metricKey(a) != metricKey(b) {
+		t.Errorf("标签顺序不同不应影响行键: %q != %q", metricKey(a), metricKey(b))
+	}
+}
+
+func TestCompareReportsVanishedAlerts(t *testing.T) {
+	previous := &Snapshot{
+		ID: "20240101_080000",
+		Data: diffTestData(
+			cpuRow("a:9100", 95, "critical"),
+			cpuRow("b:9100", 85, "warning"),
+			cpuRow("c:9100", 10, "normal"),
+		),
+	}
+	current := diffTestData(cpuRow("b:9100", 86, "warning"))
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+
+	if len(diff.Vanished) != 1 || diff.Vanished[0].Labels[0].Value != "a:9100" ||
+		diff.Vanished[0].PreviousStatus != "critical" || diff.Vanished[0].Status != "" {
+		t.Errorf("上次的告警行本次未出现时应列出: %+v", diff.Vanished)
+	}
+	if !diff.HasChanges() {
+		t.Error("存在消失的告警项时应视为有变化")
+	}
+}
+
+func TestCompareReportsDatasourceAdded(t *testing.T) {
+	previousRow := cpuRow("a:9100", 50, "normal")
+	previousRow.Datasource = "prod"
+	previous := &Snapshot{ID: "20240101_080000", Data: diffTestData(previousRow)}
+	previous.Data.Datasources = []string{"prod"}
+
+	currentRow := cpuRow("a:9100", 51, "normal")
+	currentRow.Datasource = "prod"
+	otherRow := cpuRow("b:9100", 20, "normal")
+	otherRow.Datasource = "test"
+	current := diffTestData(currentRow, otherRow)
+	current.Datasources = []string{"prod", "test"}
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+
+	if len(diff.HostsRemoved) != 0 {
+		t.Errorf("新增数据源不应使已有主机显示为消失: %v", diff.HostsRemoved)
+	}
+	if len(diff.HostsAdded) != 1 || diff.HostsAdded[0] != "test/b:9100" {
+		t.Errorf("新增主机不正确: %v", diff.HostsAdded)
+	}
+}
+
+func TestCompareReportsIgnoresPlaceholderHosts(t *testing.T) {
+	previous := &Snapshot{ID: "20240101_080000", Data: diffTestData(cpuRow("a:9100", 50, "normal"))}
+	current := diffTestData(cpuRow("a:9100", 50, "normal"), cpuRow(MissingLabelValue, 60, "normal"))
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+	if len(diff.HostsAdded) != 0 || len(diff.HostsRemoved) != 0 {
+		t.Errorf("缺少主机标签的行不应计为主机: added=%v removed=%v", diff.HostsAdded, diff.HostsRemoved)
+	}
+}
+
+func TestCompareReportsMissingRowsAreNotHosts(t *testing.T) {
+	previous := &Snapshot{ID: "20240101_080000", Data: diffTestData(cpuRow("a:9100", 50, "normal"), cpuRow("b:9100", 40, "normal"))}
+	missing := cpuRow("b:9100", 0, "critical")
+	missing.Missing = true
+	current := diffTestData(cpuRow("a:9100", 50, "normal"), missing)
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+	if len(diff.HostsRemoved) != 1 || diff.HostsRemoved[0] != "b:9100" {
+		t.Errorf("只剩缺失序列的主机应显示为消失: %v", diff.HostsRemoved)
+	}
+}
+
+func TestCompareReportsIncludesHiddenMetrics(t *testing.T) {
+	// 主机资源指标通常配置 show_in_table: false，不在 MetricOrder 中
+	hidden := func(rows ...MetricData) ReportData {
+		data := diffTestData(rows...)
+		data.MetricGroups["基础资源使用情况"].MetricOrder = nil
+		return data
+	}
+	previous := &Snapshot{
+		ID: "20240101_080000",
+		Data: hidden(
+			cpuRow("a:9100", 50, "normal"),
+			cpuRow("b:9100", 40, "normal"),
+		),
+	}
+	current := hidden(cpuRow("a:9100", 95, "critical"))
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+	if len(diff.NewAlerts) != 1 || diff.NewAlerts[0].Labels[0].Value != "a:9100" {
+		t.Errorf("未在表格中展示的指标也应参与告警对比: %+v", diff.NewAlerts)
+	}
+	if len(diff.HostsRemoved) != 1 || diff.HostsRemoved[0] != "b:9100" {
+		t.Errorf("未在表格中展示的指标也应参与主机对比: %v", diff.HostsRemoved)
+	}
+}
+
+func TestCompareReportsSkipsNonNumericChanges(t *testing.T) {
+	previous := &Snapshot{ID: "20240101_080000", Data: diffTestData(cpuRow("a:9100", 50, "critical"), cpuRow("b:9100", 40, "normal"))}
+	missing := cpuRow("a:9100", 0, "critical")
+	missing.Missing = true
+	text := cpuRow("b:9100", 0, "normal")
+	text.Text = true
+	current := diffTestData(missing, text)
+
+	diff := CompareReports(previous, &current, ComparisonConfig{Enabled: true})
+	if len(diff.Changed) != 0 {
+		t.Errorf("缺失序列和字符串结果没有数值，不应计为数值变化: %+v", diff.Changed)
+	}
+}
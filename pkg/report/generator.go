@@ -1,14 +1,15 @@
 package report
 
 import (
-	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"PromAI/pkg/units"
 )
 
 type LabelData struct {
@@ -24,25 +25,76 @@ type GroupStats struct {
 	CriticalCount int // 严重告警数量
 	WarningCount  int // 警告数量
 	TotalCount    int // 总指标数
+	ErrorCount    int // 查询失败的指标数
 }
 type MetricData struct {
 	Instance    string
 	Name        string
+	Role        string // 指标角色，如 host.cpu_usage，用于主机资源概览
+	Datasource  string // 数据来源的数据源名称
 	Description string
 	Value       float64
 	Threshold   float64
 	Unit        string
+	Display     string // 按单位换算、格式化后的显示值
+	Text        bool   // 字符串查询结果，只有 Display 有意义
+	Missing     bool   // 期望存在但查询结果中缺失的序列，Value 无意义
 	Status      string
 	StatusText  string
 	Timestamp   time.Time
 	Labels      []LabelData // 改用结构化的标签数据
+	// 命中的阈值规则，展示类指标为空
+	ThresholdText string // 阈值说明，如 "≥73.6% 警告，>92% 严重"
+	ThresholdRule string // 规则来源：默认阈值、标签选择器或 threshold_query
+	// 序列缺少的配置标签，仅 missing_label_policy 为 flag 时记录
+	MissingLabels []string
+}
+
+// MissingLabelValue 缺失的标签在报告中显示的值
+const MissingLabelValue = "-"
+
+// QueryError 查询失败或超时的指标
+type QueryError struct {
+	Group      string // 所属指标组
+	Metric     string // 指标名称
+	Datasource string // 数据源名称
+	Query      string // PromQL
+	Error      string // 错误信息
+	Timeout    bool   // 是否因超时失败
+}
+
+// DataQuality 数据质量统计，按配置顺序列出存在问题的指标
+type DataQuality struct {
+	Total         int             // 查询返回的样本数
+	Valid         int             // 数值校验通过的样本数
+	Invalid       int             // 数值校验失败被跳过的样本数
+	DiskAnomalies int             // 其中磁盘相关指标的样本数
+	MissingLabels int             // 缺少配置标签的样本数
+	Dropped       int             // 因缺少标签被丢弃的样本数
+	QueryErrors   int             // 查询失败或超时的指标数
+	Metrics       []MetricQuality // 存在无效样本或缺少标签的指标
+}
+
+// MetricQuality 单个指标的数据质量问题
+type MetricQuality struct {
+	Group         string
+	Metric        string
+	Policy        string   // 生效的 missing_label_policy
+	Invalid       int      // 数值校验失败的样本数
+	MissingLabels int      // 缺少配置标签的样本数
+	Dropped       int      // 因缺少标签被丢弃的样本数
+	Labels        []string // 缺失的标签名
 }
 
 type MetricGroup struct {
 	Type          string
 	MetricsByName map[string][]MetricData
 	MetricOrder   []string
-	Stats         GroupStats // 替换原来的 Average
+	Errors        map[string]QueryError   // 按指标名记录查询失败信息
+	Stats         GroupStats              // 替换原来的 Average
+	Trends        map[string]*MetricTrend // 按指标名记录 trend_query 的趋势数据
+	TrendOrder    []string                // 有趋势数据的指标，按配置顺序，包含不在表格中展示的指标
+	TrendCharts   map[string]*Chart       `json:"-"` // 按指标名索引的趋势折线图，由 Trends 计算
 }
 
 // 新增：主机资源聚合结构
@@ -69,17 +121,18 @@ type NetworkIOInfo struct {
 }
 
 type HostSummary struct {
-	Hostname  string
-	IP        string
-	CPUCount  int64
-	CPUUsage  float64
-	CPUStatus string // 新增：CPU使用率状态
-	MemTotal  float64
-	MemUsed   float64
-	MemUsage  float64
-	MemStatus string // 新增：内存使用率状态
-	DiskData  []DiskInfo
-	Timestamp time.Time
+	Datasource string // 主机所属数据源
+	Hostname   string
+	IP         string
+	CPUCount   int64
+	CPUUsage   float64
+	CPUStatus  string // 新增：CPU使用率状态
+	MemTotal   float64
+	MemUsed    float64
+	MemUsage   float64
+	MemStatus  string // 新增：内存使用率状态
+	DiskData   []DiskInfo
+	Timestamp  time.Time
 
 	// 新增字段
 	Uptime         float64         // 运行时间(秒)
@@ -94,9 +147,20 @@ type ReportData struct {
 	Timestamp    time.Time
 	MetricGroups map[string]*MetricGroup
 	GroupOrder   []string
-	ChartData    map[string]template.JS
+	ChartData    map[string]*Chart `json:"-"` // 按 "分组_指标名" 索引的指标图表，由快照数据重新计算，不保存
 	Project      string
-	HostSummary  []HostSummary // 新增：主机资源汇总
+	HostSummary  []HostSummary     // 新增：主机资源汇总
+	HostLabels   HostSummaryConfig // 主机资源概览使用的标签
+	QueryErrors  []QueryError      // 查询失败或超时的指标，按配置顺序
+	Datasources  []string          // 本次报告涉及的数据源，按配置顺序
+	Comparison   ComparisonConfig  // 与上一次巡检对比的配置
+	Diff         *ReportDiff       // 与上一次巡检的差异，无历史报告时为空
+	Quality      DataQuality       // 数据质量统计
+	// 趋势折线图，由快照数据重新计算，不保存
+	TrendCharts     []*Chart `json:"-"` // 全部趋势图，包括指标趋势图和主机资源趋势图
+	HostTrendCharts []*Chart `json:"-"` // 按主机汇总的资源使用率趋势图
+	// 按主机对齐的资源使用概览图表，由快照数据重新计算，不保存
+	ResourceChart *Chart `json:"-"`
 }
 
 func GetStatusText(status string) string {
@@ -112,94 +176,135 @@ func GetStatusText(status string) string {
 
 // 新增：字节格式化函数
 func formatBytes(bytes float64) string {
-	if bytes == 0 {
-		return "0 B"
-	}
-
-	unitPrefixes := []string{"B", "KB", "MB", "GB", "TB"}
-	const unitSize = 1024
-
-	unitIndex := 0
-	floatBytes := float64(bytes)
-
-	for floatBytes >= unitSize && unitIndex < len(unitPrefixes)-1 {
-		floatBytes /= unitSize
-		unitIndex++
-	}
-
-	return fmt.Sprintf("%.2f %s", floatBytes, unitPrefixes[unitIndex])
+	return units.FormatBytesValue(bytes)
 }
 
 // 新增：运行时间格式化函数
 func formatUptime(seconds float64) string {
-	if seconds <= 0 {
-		return "0秒"
-	}
+	return units.FormatDuration(seconds)
+}
+
+// 新增：速率格式化函数（用于网络和磁盘IO），输入为每秒字节数
+func formatRate(bytesPerSecond float64) string {
+	return units.FormatRateValue(bytesPerSecond)
+}
 
-	days := int(seconds) / 86400
-	hours := (int(seconds) % 86400) / 3600
-	minutes := (int(seconds) % 3600) / 60
-	secs := int(seconds) % 60
+// 新增：数值格式化函数（用于连接数等）
+func formatNumber(number int64) string {
+	return units.FormatNumberValue(float64(number))
+}
 
-	if days > 0 {
-		if hours > 0 {
-			return fmt.Sprintf("%d天%d小时%d分钟", days, hours, minutes)
+// formatLabels 将标签格式化为 "别名=值" 形式，用于对比结果等紧凑展示
+func formatLabels(labels []LabelData) string {
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		name := label.Alias
+		if name == "" {
+			name = label.Name
 		}
-		return fmt.Sprintf("%d天%d分钟", days, minutes)
-	} else if hours > 0 {
-		return fmt.Sprintf("%d小时%d分钟", hours, minutes)
-	} else if minutes > 0 {
-		return fmt.Sprintf("%d分钟%d秒", minutes, secs)
+		pairs = append(pairs, name+"="+label.Value)
 	}
-	return fmt.Sprintf("%d秒", secs)
+	return strings.Join(pairs, ", ")
 }
 
-// 新增：速率格式化函数（用于网络和磁盘IO）
-func formatRate(bytesPerSecond float64) string {
-	if bytesPerSecond == 0 {
-		return "0 B/s"
-	}
+// reportIDLayout 报告编号格式，与报告文件名中的时间戳一致
+const reportIDLayout = "20060102_150405"
 
-	// 对于已经在PromQL中换算过的MB/s单位数据，直接格式化显示
-	if bytesPerSecond < 1024 {
-		return fmt.Sprintf("%.2f MB/s", bytesPerSecond)
-	} else {
-		return fmt.Sprintf("%.2f GB/s", bytesPerSecond/1024)
-	}
+// reportFilename 返回报告编号对应的 HTML 文件路径
+func reportFilename(reportID string) string {
+	return fmt.Sprintf("reports/inspection_report_%s.html", reportID)
 }
 
-// 新增：数值格式化函数（用于连接数等）
-func formatNumber(number int64) string {
-	if number == 0 {
-		return "0"
-	}
+// IDFromFilename 从报告文件路径中提取报告编号
+func IDFromFilename(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	return strings.TrimPrefix(name, "inspection_report_")
+}
 
-	// 添加千位分隔符
-	str := fmt.Sprintf("%d", number)
-	if len(str) <= 3 {
-		return str
+func GenerateReport(data ReportData) (string, error) {
+	prepareReport(&data)
+
+	// 与上一次巡检结果对比，需在保存本次快照之前读取
+	if data.Comparison.Enabled {
+		data.Diff = compareWithLatest(&data)
 	}
 
-	var result []rune
-	for i, r := range str {
-		if i > 0 && (len(str)-i)%3 == 0 {
-			result = append(result, ',')
+	// 创建输出文件
+	reportID := time.Now().Format(reportIDLayout)
+	filename := reportFilename(reportID)
+	if err := renderReport(data, filename); err != nil {
+		return "", err
+	}
+	log.Printf("项目[%s]报告生成成功: %s", data.Project, filename)
+	renderPDFIfEnabled(filename)
+
+	// 保存结构化快照，失败不影响报告生成
+	if store := Store(); store != nil {
+		snapshot := &Snapshot{
+			ID:         reportID,
+			Project:    data.Project,
+			Timestamp:  data.Timestamp,
+			ReportFile: filename,
+			Data:       data,
+		}
+		if err := store.Save(snapshot); err != nil {
+			log.Printf("保存报告快照失败: %v", err)
 		}
-		result = append(result, r)
 	}
 
-	return string(result)
+	return filename, nil // 添加返回语句
+}
+
+// RerenderReport 使用当前模板重新渲染历史快照，无需再次查询 Prometheus
+func RerenderReport(reportID string) (string, error) {
+	store := Store()
+	if store == nil {
+		return "", fmt.Errorf("report store not configured")
+	}
+	snapshot, err := store.Load(reportID)
+	if err != nil {
+		return "", err
+	}
+
+	data := snapshot.Data
+	prepareReport(&data)
+	filename := reportFilename(snapshot.ID)
+	if err := renderReport(data, filename); err != nil {
+		return "", err
+	}
+	log.Printf("项目[%s]报告重新渲染成功: %s", data.Project, filename)
+	renderPDFIfEnabled(filename)
+	return filename, nil
 }
 
-// 新增：提取IP地址函数，从instance:9100 提取
-func extractIP(instance string) string {
-	if idx := strings.LastIndex(instance, ":"); idx != -1 {
-		return instance[:idx]
+// compareWithLatest 与同一项目最近一次快照对比
+func compareWithLatest(data *ReportData) *ReportDiff {
+	store := Store()
+	if store == nil {
+		return nil
+	}
+	metas, err := store.List()
+	if err != nil {
+		log.Printf("读取历史报告失败: %v", err)
+		return nil
+	}
+	for _, meta := range metas {
+		if meta.Project != data.Project {
+			continue
+		}
+		previous, err := store.Load(meta.ID)
+		if err != nil {
+			log.Printf("读取历史报告 %s 失败: %v", meta.ID, err)
+			return nil
+		}
+		return CompareReports(previous, data, data.Comparison)
 	}
-	return instance
+	log.Printf("项目[%s]没有历史报告，跳过对比", data.Project)
+	return nil
 }
 
-func GenerateReport(data ReportData) (string, error) {
+// prepareReport 计算分组统计、图表数据和主机资源概览
+func prepareReport(data *ReportData) {
 	log.Printf("GroupOrder: %+v", data.GroupOrder)
 	// debug日志： 确认api 接口获取的数据是正常的
 	for groupType, group := range data.MetricGroups {
@@ -226,290 +331,46 @@ func GenerateReport(data ReportData) (string, error) {
 
 		// 告警总数 = 严重告警数 + 警告告警数
 		stats.AlertCount = stats.CriticalCount + stats.WarningCount
+		stats.ErrorCount = len(group.Errors)
 		group.Stats = stats
 	}
 
-	// 处理图表数据
-	allLabels := make(map[string]bool)      // 用于存储所有唯一的标签值
-	chartData := make(map[string][]float64) // 用于存储图表数据
-	// 收集所有唯一的标签值和准备图表数据
-	labelValuesByMetric := make(map[string]map[string]bool) // 按指标存储唯一标签值
-
-	// 第一次遍历收集每个指标的唯一标签值
-	for _, group := range data.MetricGroups {
-		for metricName, metrics := range group.MetricsByName {
-			metricKey := fmt.Sprintf("%s_%s", group.Type, metricName)
-			labelValuesByMetric[metricKey] = make(map[string]bool)
-			// log.Println("指标组：", group.Type, "指标：", metricName, "指标键：", metricKey)
-			for _, metric := range metrics {
-				for _, label := range metric.Labels {
-					labelValuesByMetric[metricKey][label.Value] = true
-					// log.Println("指标组：", group.Type, "指标：", metricName, "指标键：", metricKey, "标签值：", label.Value)
-					allLabels[label.Value] = true
-
-				}
-			}
-		}
-	}
-
-	// 第二次遍历按标签值顺序生成图表数据
-	for _, group := range data.MetricGroups {
-		for metricName, metrics := range group.MetricsByName {
-			metricKey := fmt.Sprintf("%s_%s", group.Type, metricName)
-			metricValues := make(map[string]float64)
-			// log.Println("指标类型：", group.Type, "指标名称：", metricName, "指标Key：", metricKey)
-
-			// 初始化所有标签值对应的指标值为0
-			for labelValue := range labelValuesByMetric[metricKey] {
-
-				metricValues[labelValue] = 0
-
-				log.Println("标签值：", labelValue, "指标值：", metricValues[labelValue])
-			}
-
-			// 填充实际的指标值
-			for _, metric := range metrics {
-				if len(metric.Labels) > 0 {
-					metricValues[metric.Labels[0].Value] = metric.Value
-				}
-			}
-
-			// 按标签值顺序添加到图表数据
-			chartData[metricKey] = make([]float64, 0)
-			for labelValue := range labelValuesByMetric[metricKey] {
-				chartData[metricKey] = append(chartData[metricKey], metricValues[labelValue])
-			}
-			// log.Println("图表数据：", metricKey, "图表数据值：", chartData[metricKey])
-		}
-	}
-
-	// 转换标签为数组并排序
-	labels := make([]string, 0, len(allLabels))
-	for label := range allLabels {
-		labels = append(labels, label)
-	}
-	sort.Strings(labels)
-
-	// 转换为JSON
-	labelsJSON, _ := json.Marshal(labels)
-	data.ChartData["labels"] = template.JS(labelsJSON)
-	// log.Println("标签：", labels)
-	// 为每个指标生成图表数据
-	for key, values := range chartData {
-		valuesJSON, _ := json.Marshal(values)
-		data.ChartData[key] = template.JS(valuesJSON)
-	}
+	// 每个指标一张图表，标签与数值在同一结构中对齐
+	data.ChartData = buildMetricCharts(data)
+	data.ResourceChart = buildResourceChart(data)
 
 	// 按主机聚合数据
-	hostMap := make(map[string]*HostSummary)
-	for groupType, group := range data.MetricGroups {
-		if groupType != "基础资源使用情况" {
-			continue
-		}
-		for metricName, metrics := range group.MetricsByName {
-			for _, m := range metrics {
-				var instance string
-				for _, label := range m.Labels {
-					if label.Name == "instance" {
-						instance = label.Value
-						break
-					}
-				}
-				if instance == "" {
-					continue
-				}
-
-				if _, exists := hostMap[instance]; !exists {
-					hostMap[instance] = &HostSummary{
-						Hostname:     instance,
-						IP:           extractIP(instance),
-						DiskData:     make([]DiskInfo, 0),
-						DiskIOStats:  make([]DiskIOInfo, 0),
-						NetworkStats: make([]NetworkIOInfo, 0),
-						Timestamp:    m.Timestamp,
-					}
-				}
-
-				host := hostMap[instance]
-
-				// 更新最新时间戳
-				if m.Timestamp.After(host.Timestamp) {
-					host.Timestamp = m.Timestamp
-				}
-
-				//debug 日志： 确认MetricsData里的数据是正常的
-				// log.Printf("Processing metric: %s from instance %s, value: %f, status: %s", metricName, instance, m.Value, m.Status)
-				// 根据指标名填充数据
-				switch metricName {
-				case "CPU使用率":
-					host.CPUUsage = m.Value
-					host.CPUStatus = m.Status // 传递状态
-				case "CPU核心数":
-					host.CPUCount = int64(m.Value)
-				case "内存总量":
-					host.MemTotal = m.Value
-				case "内存使用量":
-					host.MemUsed = m.Value
-					if host.MemTotal > 0 {
-						host.MemUsage = (host.MemUsed / host.MemTotal) * 100
-					}
-				case "内存使用率":
-					host.MemUsage = m.Value
-					host.MemStatus = m.Status // 传递状态
-				// 新增：处理运行时间指标
-				case "运行时间":
-					host.Uptime = m.Value
-				// 新增：处理5分钟负载指标
-				case "5分钟负载":
-					host.Load5 = m.Value
-				// 新增：处理TCP连接数指标
-				case "TCP连接数":
-					host.TCPConnections = int64(m.Value)
-				// 新增：处理TCP_TW数指标
-				case "TCP_TW数":
-					host.TCPTimeWait = int64(m.Value)
-				// 新增：处理磁盘IO指标
-				case "30分钟内磁盘平均读取值", "30分钟内磁盘平均写入值":
-					var device string
-					for _, label := range m.Labels {
-						if label.Name == "device" {
-							device = label.Value
-							break
-						}
-					}
-					if device == "" {
-						continue
-					}
-
-					var diskIO *DiskIOInfo
-					for i := range host.DiskIOStats {
-						if host.DiskIOStats[i].Device == device {
-							diskIO = &host.DiskIOStats[i]
-							break
-						}
-					}
-					if diskIO == nil {
-						host.DiskIOStats = append(host.DiskIOStats, DiskIOInfo{Device: device})
-						diskIO = &host.DiskIOStats[len(host.DiskIOStats)-1]
-					}
-
-					if metricName == "30分钟内磁盘平均读取值" {
-						diskIO.AvgReadRate = m.Value
-					} else if metricName == "30分钟内磁盘平均写入值" {
-						diskIO.AvgWriteRate = m.Value
-					}
-				// 新增：处理网络IO指标
-				case "30分钟内下载速率", "30分钟内上传速率":
-					var networkDevice string
-					for _, label := range m.Labels {
-						if label.Name == "device" {
-							networkDevice = label.Value
-							break
-						}
-					}
-					if networkDevice == "" {
-						continue
-					}
-
-					var networkIO *NetworkIOInfo
-					for i := range host.NetworkStats {
-						if host.NetworkStats[i].Interface == networkDevice {
-							networkIO = &host.NetworkStats[i]
-							break
-						}
-					}
-					if networkIO == nil {
-						host.NetworkStats = append(host.NetworkStats, NetworkIOInfo{Interface: networkDevice})
-						networkIO = &host.NetworkStats[len(host.NetworkStats)-1]
-					}
-
-					if metricName == "30分钟内下载速率" {
-						networkIO.AvgDownloadRate = m.Value
-					} else if metricName == "30分钟内上传速率" {
-						networkIO.AvgUploadRate = m.Value
-					}
-				case "磁盘总量", "磁盘使用量", "磁盘使用率":
-					var mountPoint string
-					for _, label := range m.Labels {
-						if label.Name == "mountpoint" {
-							mountPoint = label.Value
-							break
-						}
-					}
-					if mountPoint == "" {
-						continue
-					}
-
-					var disk *DiskInfo
-					for i := range host.DiskData {
-						if host.DiskData[i].MountPoint == mountPoint {
-							disk = &host.DiskData[i]
-							break
-						}
-					}
-					if disk == nil {
-						host.DiskData = append(host.DiskData, DiskInfo{MountPoint: mountPoint})
-						disk = &host.DiskData[len(host.DiskData)-1]
-					}
-
-					if metricName == "磁盘总量" {
-						disk.DiskTotal = m.Value
-					} else if metricName == "磁盘使用量" {
-						disk.DiskUsed = m.Value
-					} else if metricName == "磁盘使用率" {
-						disk.DiskUsage = m.Value
-						disk.Status = m.Status
-					}
-				}
-			}
-		}
-	}
-
-	// 转换为切片
-	data.HostSummary = make([]HostSummary, 0, len(hostMap))
-	for _, h := range hostMap {
-		data.HostSummary = append(data.HostSummary, *h)
-	}
+	data.HostSummary = buildHostSummary(data.MetricGroups, data.HostLabels)
+	buildTrendCharts(data)
+}
 
+// renderReport 渲染 HTML 报告到指定文件
+func renderReport(data ReportData, filename string) error {
 	// // ✅ 注册模板函数
 	funcMap := template.FuncMap{
 		"formatBytes":  formatBytes,
 		"formatUptime": formatUptime,
 		"formatRate":   formatRate,
 		"formatNumber": formatNumber,
+		"formatLabels": formatLabels,
+		"statusText":   GetStatusText,
+		"chartKey":     metricChartKey,
 	}
 
 	tmpl, err := template.New("report.html").Funcs(funcMap).ParseFiles("templates/report.html")
 	if err != nil {
-		return "", fmt.Errorf("parsing template: %w", err)
+		return fmt.Errorf("parsing template: %w", err)
 	}
 
-	// // 生成报告
-	// tmpl, err := template.ParseFiles("templates/report.html")
-	// if err != nil {
-	// 	return "", fmt.Errorf("parsing template: %w", err)
-	// }
-
-	// 创建输出文件
-	filename := fmt.Sprintf("reports/inspection_report_%s.html", time.Now().Format("20060102_150405"))
 	file, err := os.Create(filename)
 	if err != nil {
-		return "", fmt.Errorf("creating output file: %w", err)
+		return fmt.Errorf("creating output file: %w", err)
 	}
 	defer file.Close()
 
 	// 执行模板
 	if err := tmpl.Execute(file, data); err != nil {
-		return "", fmt.Errorf("executing template: %w", err)
+		return fmt.Errorf("executing template: %w", err)
 	}
-
-	// log.Println("Report generated successfully:", filename)
-	// debug 日志： 确认最后生成的数据是正常的
-	// for _, h := range data.HostSummary {
-	// 	log.Printf("Host: %s, CPUCount: %d, MemTotal: %f, MemUsed: %f, DiskData: %d",
-	// 		h.Hostname, h.CPUCount, h.MemTotal, h.MemUsed, len(h.DiskData))
-	// }
-	log.Printf("项目[%s]报告生成成功: %s", data.Project, filename)
-
-	return filename, nil // 添加返回语句
+	return nil
 }
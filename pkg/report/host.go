@@ -0,0 +1,250 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"PromAI/pkg/units"
+)
+
+// 主机资源概览使用的指标角色，对应 MetricConfig.Role
+const (
+	RoleCPUUsage       = "host.cpu_usage"
+	RoleCPUCount       = "host.cpu_count"
+	RoleMemTotal       = "host.mem.total"
+	RoleMemUsed        = "host.mem.used"
+	RoleMemUsage       = "host.mem.usage"
+	RoleUptime         = "host.uptime"
+	RoleLoad5          = "host.load5"
+	RoleTCPConnections = "host.tcp.established"
+	RoleTCPTimeWait    = "host.tcp.time_wait"
+	RoleDiskTotal      = "host.disk.total"
+	RoleDiskUsed       = "host.disk.used"
+	RoleDiskUsage      = "host.disk.usage"
+	RoleDiskRead       = "host.diskio.read"
+	RoleDiskWrite      = "host.diskio.write"
+	RoleNetRx          = "host.net.rx"
+	RoleNetTx          = "host.net.tx"
+)
+
+// HostRolePrefix 主机资源角色前缀
+const HostRolePrefix = "host."
+
+// HostSummaryConfig 主机资源概览的标签配置
+type HostSummaryConfig struct {
+	HostLabel       string `yaml:"host_label"`        // 区分主机的标签，默认 instance
+	MountpointLabel string `yaml:"mountpoint_label"`  // 区分挂载点的标签，默认 mountpoint
+	DiskDeviceLabel string `yaml:"disk_device_label"` // 区分磁盘设备的标签，默认 device
+	NetDeviceLabel  string `yaml:"net_device_label"`  // 区分网卡的标签，默认 device
+}
+
+// WithDefaults 返回填充默认标签后的配置
+func (c HostSummaryConfig) WithDefaults() HostSummaryConfig {
+	if c.HostLabel == "" {
+		c.HostLabel = "instance"
+	}
+	if c.MountpointLabel == "" {
+		c.MountpointLabel = "mountpoint"
+	}
+	if c.DiskDeviceLabel == "" {
+		c.DiskDeviceLabel = "device"
+	}
+	if c.NetDeviceLabel == "" {
+		c.NetDeviceLabel = "device"
+	}
+	return c
+}
+
+// KnownHostRole 判断是否为支持的主机资源角色
+func KnownHostRole(role string) bool {
+	switch role {
+	case RoleCPUUsage, RoleCPUCount, RoleMemTotal, RoleMemUsed, RoleMemUsage, RoleUptime, RoleLoad5,
+		RoleTCPConnections, RoleTCPTimeWait, RoleDiskTotal, RoleDiskUsed, RoleDiskUsage,
+		RoleDiskRead, RoleDiskWrite, RoleNetRx, RoleNetTx:
+		return true
+	}
+	return false
+}
+
+// IsHostRole 判断角色是否用于主机资源概览
+func IsHostRole(role string) bool {
+	return strings.HasPrefix(role, HostRolePrefix)
+}
+
+// labelValue 按标签名查找标签值
+func labelValue(labels []LabelData, name string) string {
+	for _, label := range labels {
+		if label.Name == name {
+			// 按 placeholder/flag 保留的序列缺少该标签，不能作为主机、挂载点或设备
+			if label.Value == MissingLabelValue {
+				return ""
+			}
+			return label.Value
+		}
+	}
+	return ""
+}
+
+// 新增：提取IP地址函数，从instance:9100 提取
+func extractIP(instance string) string {
+	if idx := strings.LastIndex(instance, ":"); idx != -1 {
+		return instance[:idx]
+	}
+	return instance
+}
+
+// hostKey 主机的唯一键。不同数据源可能存在同名实例，按数据源区分主机
+func hostKey(datasource, instance string) string {
+	return datasource + "/" + instance
+}
+
+// buildHostSummary 按指标角色将各组数据聚合为主机资源概览
+func buildHostSummary(groups map[string]*MetricGroup, hostLabels HostSummaryConfig) []HostSummary {
+	hostLabels = hostLabels.WithDefaults()
+	hostMap := make(map[string]*HostSummary)
+	memUsageSet := make(map[string]bool)
+
+	for _, group := range groups {
+		for _, metrics := range group.MetricsByName {
+			for _, m := range metrics {
+				// 缺失的序列没有数值，只在指标表格中显示
+				if !IsHostRole(m.Role) || m.Missing {
+					continue
+				}
+				instance := labelValue(m.Labels, hostLabels.HostLabel)
+				if instance == "" {
+					continue
+				}
+				key := hostKey(m.Datasource, instance)
+
+				if _, exists := hostMap[key]; !exists {
+					hostMap[key] = &HostSummary{
+						Datasource:   m.Datasource,
+						Hostname:     instance,
+						IP:           extractIP(instance),
+						DiskData:     make([]DiskInfo, 0),
+						DiskIOStats:  make([]DiskIOInfo, 0),
+						NetworkStats: make([]NetworkIOInfo, 0),
+						Timestamp:    m.Timestamp,
+					}
+				}
+
+				host := hostMap[key]
+
+				// 更新最新时间戳
+				if m.Timestamp.After(host.Timestamp) {
+					host.Timestamp = m.Timestamp
+				}
+
+				switch m.Role {
+				case RoleCPUUsage:
+					host.CPUUsage = m.Value
+					host.CPUStatus = m.Status // 传递状态
+				case RoleCPUCount:
+					host.CPUCount = int64(m.Value)
+				case RoleMemTotal:
+					host.MemTotal = units.ToBase(m.Value, m.Unit)
+				case RoleMemUsed:
+					host.MemUsed = units.ToBase(m.Value, m.Unit)
+				case RoleMemUsage:
+					host.MemUsage = m.Value
+					host.MemStatus = m.Status // 传递状态
+					memUsageSet[key] = true
+				case RoleUptime:
+					host.Uptime = units.ToBase(m.Value, m.Unit)
+				case RoleLoad5:
+					host.Load5 = m.Value
+				case RoleTCPConnections:
+					host.TCPConnections = int64(m.Value)
+				case RoleTCPTimeWait:
+					host.TCPTimeWait = int64(m.Value)
+				case RoleDiskRead, RoleDiskWrite:
+					device := labelValue(m.Labels, hostLabels.DiskDeviceLabel)
+					if device == "" {
+						continue
+					}
+					diskIO := findDiskIO(host, device)
+					if m.Role == RoleDiskRead {
+						diskIO.AvgReadRate = units.ToBase(m.Value, m.Unit)
+					} else {
+						diskIO.AvgWriteRate = units.ToBase(m.Value, m.Unit)
+					}
+				case RoleNetRx, RoleNetTx:
+					networkDevice := labelValue(m.Labels, hostLabels.NetDeviceLabel)
+					if networkDevice == "" {
+						continue
+					}
+					networkIO := findNetworkIO(host, networkDevice)
+					if m.Role == RoleNetRx {
+						networkIO.AvgDownloadRate = units.ToBase(m.Value, m.Unit)
+					} else {
+						networkIO.AvgUploadRate = units.ToBase(m.Value, m.Unit)
+					}
+				case RoleDiskTotal, RoleDiskUsed, RoleDiskUsage:
+					mountPoint := labelValue(m.Labels, hostLabels.MountpointLabel)
+					if mountPoint == "" {
+						continue
+					}
+					disk := findDisk(host, mountPoint)
+					switch m.Role {
+					case RoleDiskTotal:
+						disk.DiskTotal = units.ToBase(m.Value, m.Unit)
+					case RoleDiskUsed:
+						disk.DiskUsed = units.ToBase(m.Value, m.Unit)
+					case RoleDiskUsage:
+						disk.DiskUsage = m.Value
+						disk.Status = m.Status
+					}
+				}
+			}
+		}
+	}
+
+	// 转换为切片，按主机排序保证输出稳定
+	summary := make([]HostSummary, 0, len(hostMap))
+	for key, h := range hostMap {
+		// 未配置内存使用率时，按使用量/总量计算
+		if !memUsageSet[key] && h.MemTotal > 0 {
+			h.MemUsage = (h.MemUsed / h.MemTotal) * 100
+		}
+		sort.Slice(h.DiskData, func(i, j int) bool { return h.DiskData[i].MountPoint < h.DiskData[j].MountPoint })
+		summary = append(summary, *h)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Datasource != summary[j].Datasource {
+			return summary[i].Datasource < summary[j].Datasource
+		}
+		return summary[i].Hostname < summary[j].Hostname
+	})
+	return summary
+}
+
+func findDisk(host *HostSummary, mountPoint string) *DiskInfo {
+	for i := range host.DiskData {
+		if host.DiskData[i].MountPoint == mountPoint {
+			return &host.DiskData[i]
+		}
+	}
+	host.DiskData = append(host.DiskData, DiskInfo{MountPoint: mountPoint})
+	return &host.DiskData[len(host.DiskData)-1]
+}
+
+func findDiskIO(host *HostSummary, device string) *DiskIOInfo {
+	for i := range host.DiskIOStats {
+		if host.DiskIOStats[i].Device == device {
+			return &host.DiskIOStats[i]
+		}
+	}
+	host.DiskIOStats = append(host.DiskIOStats, DiskIOInfo{Device: device})
+	return &host.DiskIOStats[len(host.DiskIOStats)-1]
+}
+
+func findNetworkIO(host *HostSummary, device string) *NetworkIOInfo {
+	for i := range host.NetworkStats {
+		if host.NetworkStats[i].Interface == device {
+			return &host.NetworkStats[i]
+		}
+	}
+	host.NetworkStats = append(host.NetworkStats, NetworkIOInfo{Interface: device})
+	return &host.NetworkStats[len(host.NetworkStats)-1]
+}
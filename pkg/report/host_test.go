@@ -0,0 +1,50 @@
+package report
+
+import "testing"
+
+func TestBuildHostSummarySkipsPlaceholderLabels(t *testing.T) {
+	// missing_label_policy 为 placeholder/flag 时，缺失的标签值为 "-"
+	row := func(role string, value float64, labels ...LabelData) MetricData {
+		return MetricData{Role: role, Value: value, Labels: labels}
+	}
+	label := func(name, value string) LabelData { return LabelData{Name: name, Value: value} }
+
+	groups := map[string]*MetricGroup{
+		"host": {
+			Type: "host",
+			MetricsByName: map[string][]MetricData{
+				"CPU使用率": {
+					row(RoleCPUUsage, 10, label("instance", "a:9100")),
+					row(RoleCPUUsage, 20, label("instance", MissingLabelValue)),
+				},
+				"磁盘使用率": {
+					row(RoleDiskUsage, 30, label("instance", "a:9100"), label("mountpoint", "/")),
+					row(RoleDiskUsage, 40, label("instance", "a:9100"), label("mountpoint", MissingLabelValue)),
+				},
+				"磁盘读取": {
+					row(RoleDiskRead, 1, label("instance", "a:9100"), label("device", "sda")),
+					row(RoleDiskRead, 2, label("instance", "a:9100"), label("device", MissingLabelValue)),
+				},
+				"网络下载": {
+					row(RoleNetRx, 3, label("instance", "a:9100"), label("device", "eth0")),
+					row(RoleNetRx, 4, label("instance", "a:9100"), label("device", MissingLabelValue)),
+				},
+			},
+		},
+	}
+
+	summary := buildHostSummary(groups, HostSummaryConfig{})
+	if len(summary) != 1 || summary[0].Hostname != "a:9100" {
+		t.Fatalf("缺少主机标签的行不应生成主机: %+v", summary)
+	}
+	host := summary[0]
+	if len(host.DiskData) != 1 || host.DiskData[0].MountPoint != "/" {
+		t.Errorf("缺少挂载点标签的行不应生成挂载点: %+v", host.DiskData)
+	}
+	if len(host.DiskIOStats) != 1 || host.DiskIOStats[0].Device != "sda" {
+		t.Errorf("缺少设备标签的行不应生成磁盘设备: %+v", host.DiskIOStats)
+	}
+	if len(host.NetworkStats) != 1 || host.NetworkStats[0].Interface != "eth0" {
+		t.Errorf("缺少网卡标签的行不应生成网卡: %+v", host.NetworkStats)
+	}
+}
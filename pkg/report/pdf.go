@@ -0,0 +1,123 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPDFTimeout 默认的 PDF 渲染超时时间
+const defaultPDFTimeout = 60 * time.Second
+
+// PDFConfig PDF 导出配置，由配置文件中的 pdf 部分转换而来
+type PDFConfig struct {
+	Enabled    bool
+	ChromePath string        // Chromium 路径，默认读取 CHROMEDP_CHROME_PATH 环境变量
+	Timeout    time.Duration // 渲染超时时间，默认 60s
+}
+
+var (
+	pdfMu     sync.RWMutex
+	pdfConfig PDFConfig
+)
+
+// SetPDFConfig 设置 PDF 导出配置
+func SetPDFConfig(cfg PDFConfig) {
+	pdfMu.Lock()
+	defer pdfMu.Unlock()
+	pdfConfig = cfg
+}
+
+func getPDFConfig() PDFConfig {
+	pdfMu.RLock()
+	defer pdfMu.RUnlock()
+	return pdfConfig
+}
+
+// PDFPath 返回 HTML 报告对应的 PDF 文件路径
+func PDFPath(htmlPath string) string {
+	return strings.TrimSuffix(htmlPath, filepath.Ext(htmlPath)) + ".pdf"
+}
+
+// chromePath 按配置、环境变量、常见安装路径的顺序查找 Chromium
+func (c PDFConfig) chromePath() (string, error) {
+	if c.ChromePath != "" {
+		return c.ChromePath, nil
+	}
+	if path := os.Getenv("CHROMEDP_CHROME_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range []string{"chromium-browser", "chromium", "google-chrome", "google-chrome-stable"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("chromium not found, set pdf.chrome_path or CHROMEDP_CHROME_PATH")
+}
+
+// RenderPDF 使用无头 Chromium 将 HTML 报告打印为 PDF，样式由报告模板中的打印 CSS 控制
+func RenderPDF(htmlPath, pdfPath string, cfg PDFConfig) error {
+	chrome, err := cfg.chromePath()
+	if err != nil {
+		return err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultPDFTimeout
+	}
+
+	absHTML, err := filepath.Abs(htmlPath)
+	if err != nil {
+		return fmt.Errorf("resolving report path: %w", err)
+	}
+	absPDF, err := filepath.Abs(pdfPath)
+	if err != nil {
+		return fmt.Errorf("resolving pdf path: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, chrome,
+		"--headless",
+		"--disable-gpu",
+		"--no-sandbox",
+		"--no-pdf-header-footer",
+		"--virtual-time-budget=10000", // 等待图表脚本渲染完成
+		"--print-to-pdf="+absPDF,
+		"file://"+filepath.ToSlash(absHTML),
+	)
+	// Chromium 子进程可能在超时后继续占用输出管道
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("rendering pdf timed out after %s", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("rendering pdf: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(absPDF); err != nil {
+		return fmt.Errorf("pdf not created: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// renderPDFIfEnabled 按配置在 HTML 报告旁生成 PDF，失败不影响 HTML 报告
+func renderPDFIfEnabled(htmlPath string) {
+	cfg := getPDFConfig()
+	if !cfg.Enabled {
+		return
+	}
+	pdfPath := PDFPath(htmlPath)
+	if err := RenderPDF(htmlPath, pdfPath, cfg); err != nil {
+		log.Printf("生成 PDF 报告失败: %v", err)
+		return
+	}
+	log.Printf("PDF 报告生成成功: %s", pdfPath)
+}
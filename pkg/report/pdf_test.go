@@ -0,0 +1,75 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFakeChrome 生成模拟 Chromium 的脚本，将收到的参数写入 --print-to-pdf 指定的文件
+func writeFakeChrome(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("模拟脚本依赖 sh")
+	}
+	path := filepath.Join(t.TempDir(), "chromium")
+	script := "#!/bin/sh\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPDFPath(t *testing.T) {
+	got := PDFPath("reports/inspection_report_20240101_080000.html")
+	if got != "reports/inspection_report_20240101_080000.pdf" {
+		t.Errorf("PDF 路径不正确: %s", got)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	chrome := writeFakeChrome(t, `for arg in "$@"; do
+  case "$arg" in
+    --print-to-pdf=*) echo "$@" > "${arg#--print-to-pdf=}" ;;
+  esac
+done`)
+
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "report.html")
+	if err := os.WriteFile(htmlPath, []byte("<html></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pdfPath := PDFPath(htmlPath)
+
+	if err := RenderPDF(htmlPath, pdfPath, PDFConfig{ChromePath: chrome}); err != nil {
+		t.Fatalf("生成 PDF 失败: %v", err)
+	}
+	args, err := os.ReadFile(pdfPath)
+	if err != nil {
+		t.Fatalf("PDF 文件未生成: %v", err)
+	}
+	for _, want := range []string{"--headless", "file://" + htmlPath} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("Chromium 参数缺少 %q: %s", want, args)
+		}
+	}
+}
+
+func TestRenderPDFErrors(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "report.html")
+
+	failing := writeFakeChrome(t, `echo "crashed" >&2; exit 1`)
+	if err := RenderPDF(htmlPath, PDFPath(htmlPath), PDFConfig{ChromePath: failing}); err == nil || !strings.Contains(err.Error(), "crashed") {
+		t.Errorf("Chromium 失败时应返回其输出，实际: %v", err)
+	}
+
+	slow := writeFakeChrome(t, `exec sleep 5`)
+	err := RenderPDF(htmlPath, PDFPath(htmlPath), PDFConfig{ChromePath: slow, Timeout: 100 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("超时应返回错误，实际: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSnapshotNotFound 快照不存在
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot 一次巡检的结构化结果，与 HTML 报告一一对应
+type Snapshot struct {
+	ID         string     `json:"id"`          // 报告编号，与报告文件名中的时间戳一致
+	Project    string     `json:"project"`     // 项目名称
+	Timestamp  time.Time  `json:"timestamp"`   // 采集时间
+	ReportFile string     `json:"report_file"` // 对应的 HTML 报告路径
+	Data       ReportData `json:"data"`        // 指标值、状态、标签、阈值与主机概览
+}
+
+// SnapshotMeta 快照摘要，用于列出历史报告
+type SnapshotMeta struct {
+	ID            string    `json:"id"`
+	Project       string    `json:"project"`
+	Timestamp     time.Time `json:"timestamp"`
+	ReportFile    string    `json:"report_file"`
+	TotalCount    int       `json:"total_count"`
+	WarningCount  int       `json:"warning_count"`
+	CriticalCount int       `json:"critical_count"`
+	ErrorCount    int       `json:"error_count"`
+}
+
+// Meta 汇总快照中各组的统计信息
+func (s *Snapshot) Meta() SnapshotMeta {
+	meta := SnapshotMeta{
+		ID:         s.ID,
+		Project:    s.Project,
+		Timestamp:  s.Timestamp,
+		ReportFile: s.ReportFile,
+	}
+	for _, group := range s.Data.MetricGroups {
+		meta.TotalCount += group.Stats.TotalCount
+		meta.WarningCount += group.Stats.WarningCount
+		meta.CriticalCount += group.Stats.CriticalCount
+		meta.ErrorCount += group.Stats.ErrorCount
+	}
+	return meta
+}
+
+// ReportStore 历史报告存储
+type ReportStore interface {
+	Save(snapshot *Snapshot) error
+	Load(id string) (*Snapshot, error)
+	List() ([]SnapshotMeta, error) // 按时间倒序
+	Latest() (*Snapshot, error)
+}
+
+// StoreConfig 历史报告存储配置
+type StoreConfig struct {
+	Dir string `yaml:"dir"` // 快照目录，默认 reports/history，不受 report_cleanup 清理
+}
+
+// DefaultStoreDir 默认快照目录。报告清理不进入 reports 的子目录，快照在此长期保留
+const DefaultStoreDir = "reports/history"
+
+var (
+	storeMu      sync.RWMutex
+	defaultStore ReportStore = NewFileStore(DefaultStoreDir)
+)
+
+// SetStore 设置全局报告存储，传入 nil 时不保存快照
+func SetStore(store ReportStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	defaultStore = store
+}
+
+// Store 返回全局报告存储
+func Store() ReportStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return defaultStore
+}
+
+const (
+	snapshotPrefix = "inspection_report_"
+	snapshotSuffix = ".json"
+)
+
+// FileStore 以 JSON 文件保存快照，文件名与 HTML 报告对应
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore 创建基于目录的快照存储
+func NewFileStore(dir string) *FileStore {
+	if dir == "" {
+		dir = DefaultStoreDir
+	}
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, snapshotPrefix+id+snapshotSuffix)
+}
+
+// Save 保存快照，先写临时文件再重命名，避免读取到写了一半的文件
+func (s *FileStore) Save(snapshot *Snapshot) error {
+	if snapshot.ID == "" {
+		return fmt.Errorf("snapshot id is empty")
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("creating snapshot dir: %w", err)
+	}
+
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("creating snapshot file: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing snapshot file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing snapshot file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(snapshot.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("saving snapshot file: %w", err)
+	}
+	return nil
+}
+
+// Load 按编号读取快照
+func (s *FileStore) Load(id string) (*Snapshot, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return nil, ErrSnapshotNotFound
+	}
+	content, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("decoding snapshot %s: %w", id, err)
+	}
+	return &snapshot, nil
+}
+
+// isSnapshotFile 文件名是否为快照文件
+func isSnapshotFile(name string) bool {
+	return strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix)
+}
+
+// ids 返回目录中所有快照编号，按时间倒序
+func (s *FileStore) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot dir: %w", err)
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isSnapshotFile(name) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix))
+	}
+	// 编号为时间戳格式，字符串倒序即时间倒序
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// List 列出所有快照摘要，按时间倒序
+func (s *FileStore) List() ([]SnapshotMeta, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	metas := make([]SnapshotMeta, 0, len(ids))
+	for _, id := range ids {
+		snapshot, err := s.Load(id)
+		if err != nil {
+			// 损坏的快照不影响其他快照
+			continue
+		}
+		metas = append(metas, snapshot.Meta())
+	}
+	return metas, nil
+}
+
+// Latest 返回最近一次快照
+func (s *FileStore) Latest() (*Snapshot, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if snapshot, err := s.Load(id); err == nil {
+			return snapshot, nil
+		}
+	}
+	return nil, ErrSnapshotNotFound
+}
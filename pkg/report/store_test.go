@@ -0,0 +1,100 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSnapshot(id string, status string) *Snapshot {
+	ts, _ := time.Parse(reportIDLayout, id)
+	return &Snapshot{
+		ID:         id,
+		Project:    "测试项目",
+		Timestamp:  ts,
+		ReportFile: reportFilename(id),
+		Data: ReportData{
+			Timestamp:  ts,
+			Project:    "测试项目",
+			GroupOrder: []string{"基础资源使用情况"},
+			MetricGroups: map[string]*MetricGroup{
+				"基础资源使用情况": {
+					Type:        "基础资源使用情况",
+					MetricOrder: []string{"CPU使用率"},
+					MetricsByName: map[string][]MetricData{
+						"CPU使用率": {{
+							Name:      "CPU使用率",
+							Value:     91.5,
+							Threshold: 80,
+							Unit:      "%",
+							Status:    status,
+							Labels:    []LabelData{{Name: "instance", Alias: "实例", Value: "10.0.0.1:9100"}},
+						}},
+					},
+					Stats: GroupStats{TotalCount: 1, CriticalCount: 1},
+				},
+			},
+		},
+	}
+}
+
+func TestFileStoreSaveLoadList(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+
+	if _, err := store.Latest(); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("空目录应返回 ErrSnapshotNotFound，实际: %v", err)
+	}
+
+	for _, id := range []string{"20240101_080000", "20240103_080000", "20240102_080000"} {
+		if err := store.Save(newTestSnapshot(id, "critical")); err != nil {
+			t.Fatalf("保存快照失败: %v", err)
+		}
+	}
+
+	loaded, err := store.Load("20240102_080000")
+	if err != nil {
+		t.Fatalf("读取快照失败: %v", err)
+	}
+	metric := loaded.Data.MetricGroups["基础资源使用情况"].MetricsByName["CPU使用率"][0]
+	if metric.Value != 91.5 || metric.Status != "critical" || metric.Labels[0].Value != "10.0.0.1:9100" {
+		t.Errorf("快照内容不正确: %+v", metric)
+	}
+
+	metas, err := store.List()
+	if err != nil {
+		t.Fatalf("列出快照失败: %v", err)
+	}
+	want := []string{"20240103_080000", "20240102_080000", "20240101_080000"}
+	if len(metas) != len(want) {
+		t.Fatalf("快照数量不正确: %d", len(metas))
+	}
+	for i, meta := range metas {
+		if meta.ID != want[i] {
+			t.Errorf("第 %d 个快照应为 %s，实际 %s", i, want[i], meta.ID)
+		}
+		if meta.TotalCount != 1 || meta.CriticalCount != 1 {
+			t.Errorf("快照摘要统计不正确: %+v", meta)
+		}
+	}
+
+	latest, err := store.Latest()
+	if err != nil || latest.ID != "20240103_080000" {
+		t.Errorf("最近快照不正确: %v, %v", latest, err)
+	}
+}
+
+func TestFileStoreLoadRejectsInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "reports"))
+	if err := os.WriteFile(filepath.Join(dir, "inspection_report_x.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"", "../inspection_report_x", "missing"} {
+		if _, err := store.Load(id); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Errorf("编号 %q 应返回 ErrSnapshotNotFound，实际: %v", id, err)
+		}
+	}
+}
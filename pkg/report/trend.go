@@ -0,0 +1,185 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TrendPoint 趋势数据中的一个采样点
+type TrendPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// TrendSeries trend_query 返回的一条时间序列
+type TrendSeries struct {
+	Labels []LabelData
+	Points []TrendPoint
+}
+
+// MetricTrend 指标在巡检窗口内的趋势数据
+type MetricTrend struct {
+	Start      time.Time
+	End        time.Time
+	Step       time.Duration
+	Unit       string // 趋势数值的单位，配置了 target_unit 时为换算后的单位
+	Role       string // 指标角色，用于主机资源趋势图
+	Datasource string
+	Series     []TrendSeries
+	Error      string // 趋势查询失败的原因，不影响即时查询结果
+}
+
+// namedSeries 带显示名称的时间序列
+type namedSeries struct {
+	name   string
+	points []TrendPoint
+}
+
+// hostTrendRoles 绘制主机资源趋势图的指标角色，均为百分比
+var hostTrendRoles = map[string]bool{
+	RoleCPUUsage:  true,
+	RoleMemUsage:  true,
+	RoleDiskUsage: true,
+}
+
+// trendTimeLayout 趋势图时间轴格式
+const trendTimeLayout = "01-02 15:04"
+
+// lineChart 按全部序列的采样时间生成统一的时间轴，各序列按时间对齐
+func lineChart(id, title, unit string, series []namedSeries) *Chart {
+	timeSet := make(map[int64]bool)
+	for _, s := range series {
+		for _, p := range s.points {
+			timeSet[p.Time.UnixMilli()] = true
+		}
+	}
+	times := make([]int64, 0, len(timeSet))
+	for t := range timeSet {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	index := make(map[int64]int, len(times))
+	chart := &Chart{
+		ID:     id,
+		Title:  title,
+		Unit:   unit,
+		Labels: make([]string, len(times)),
+		Series: make([]ChartSeries, 0, len(series)),
+	}
+	for i, t := range times {
+		index[t] = i
+		chart.Labels[i] = time.UnixMilli(t).Format(trendTimeLayout)
+	}
+	for _, s := range series {
+		values := make([]*float64, len(times))
+		for _, p := range s.points {
+			value := p.Value
+			values[index[p.Time.UnixMilli()]] = &value
+		}
+		chart.Series = append(chart.Series, ChartSeries{Name: s.name, Values: values})
+	}
+	return chart
+}
+
+// seriesName 返回趋势序列的显示名称，没有标签时使用指标名
+func seriesName(metricName string, series TrendSeries) string {
+	if name := chartLabel(series.Labels); name != "" {
+		return name
+	}
+	return metricName
+}
+
+// trendNames 返回组内有趋势数据的指标：先按表格中的指标顺序，再按配置顺序追加未在表格中展示的指标。
+// 旧快照没有 TrendOrder，其余指标按名称排序
+func trendNames(group *MetricGroup) []string {
+	seen := make(map[string]bool, len(group.Trends))
+	names := make([]string, 0, len(group.Trends))
+	add := func(name string) {
+		if _, exists := group.Trends[name]; exists && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range group.MetricOrder {
+		add(name)
+	}
+	for _, name := range group.TrendOrder {
+		add(name)
+	}
+
+	var rest []string
+	for name := range group.Trends {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// buildTrendCharts 为每个配置了 trend_query 的指标生成折线图，并按主机汇总资源使用率趋势
+func buildTrendCharts(data *ReportData) {
+	data.TrendCharts = nil
+	data.HostTrendCharts = nil
+
+	type hostKey struct{ datasource, host string }
+	hostSeries := make(map[hostKey][]namedSeries)
+	hostLabels := data.HostLabels.WithDefaults()
+
+	for groupIndex, groupType := range data.GroupOrder {
+		group := data.MetricGroups[groupType]
+		if group == nil {
+			continue
+		}
+		group.TrendCharts = make(map[string]*Chart)
+
+		for metricIndex, name := range trendNames(group) {
+			trend := group.Trends[name]
+			if trend == nil || len(trend.Series) == 0 {
+				continue
+			}
+
+			series := make([]namedSeries, 0, len(trend.Series))
+			for _, s := range trend.Series {
+				series = append(series, namedSeries{name: seriesName(name, s), points: s.Points})
+			}
+			chart := lineChart(fmt.Sprintf("trend-%d-%d", groupIndex, metricIndex), name+" 趋势", trend.Unit, series)
+			group.TrendCharts[name] = chart
+			data.TrendCharts = append(data.TrendCharts, chart)
+
+			if !hostTrendRoles[trend.Role] {
+				continue
+			}
+			for _, s := range trend.Series {
+				host := labelValue(s.Labels, hostLabels.HostLabel)
+				if host == "" {
+					continue
+				}
+				lineName := name
+				if trend.Role == RoleDiskUsage {
+					lineName += " " + labelValue(s.Labels, hostLabels.MountpointLabel)
+				}
+				key := hostKey{trend.Datasource, host}
+				hostSeries[key] = append(hostSeries[key], namedSeries{name: lineName, points: s.Points})
+			}
+		}
+	}
+
+	keys := make([]hostKey, 0, len(hostSeries))
+	for key := range hostSeries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].datasource != keys[j].datasource {
+			return keys[i].datasource < keys[j].datasource
+		}
+		return keys[i].host < keys[j].host
+	})
+	for i, key := range keys {
+		chart := lineChart(fmt.Sprintf("host-trend-%d", i), key.host+" 资源使用率趋势", "%", hostSeries[key])
+		data.HostTrendCharts = append(data.HostTrendCharts, chart)
+		data.TrendCharts = append(data.TrendCharts, chart)
+	}
+}
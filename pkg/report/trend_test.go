@@ -0,0 +1,133 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineChartAlignsSeriesByTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	chart := lineChart("c", "title", "%", []namedSeries{
+		{name: "a", points: []TrendPoint{{at(0), 1}, {at(10), 2}}},
+		{name: "b", points: []TrendPoint{{at(5), 3}, {at(10), 4}}},
+	})
+
+	wantLabels := []string{"01-01 08:00", "01-01 08:05", "01-01 08:10"}
+	if len(chart.Labels) != len(wantLabels) {
+		t.Fatalf("时间轴不正确: %v", chart.Labels)
+	}
+	for i, want := range wantLabels {
+		if chart.Labels[i] != want {
+			t.Errorf("Labels[%d] = %s, 期望 %s", i, chart.Labels[i], want)
+		}
+	}
+
+	want := map[string][]interface{}{
+		"a": {1.0, nil, 2.0},
+		"b": {nil, 3.0, 4.0},
+	}
+	for _, series := range chart.Series {
+		for i, value := range series.Values {
+			expected := want[series.Name][i]
+			if expected == nil {
+				if value != nil {
+					t.Errorf("%s[%d] 应该为空，实际 %v", series.Name, i, *value)
+				}
+				continue
+			}
+			if value == nil || *value != expected.(float64) {
+				t.Errorf("%s[%d] = %v, 期望 %v", series.Name, i, value, expected)
+			}
+		}
+	}
+}
+
+func TestBuildTrendCharts(t *testing.T) {
+	now := time.Now()
+	series := func(host, mountpoint string, value float64) TrendSeries {
+		labels := []LabelData{{Name: "instance", Alias: "节点", Value: host}}
+		if mountpoint != "" {
+			labels = append(labels, LabelData{Name: "mountpoint", Alias: "挂载点", Value: mountpoint})
+		}
+		return TrendSeries{Labels: labels, Points: []TrendPoint{{now, value}}}
+	}
+
+	data := &ReportData{
+		GroupOrder: []string{"host"},
+		HostLabels: HostSummaryConfig{}.WithDefaults(),
+		MetricGroups: map[string]*MetricGroup{
+			"host": {
+				Type: "host",
+				Trends: map[string]*MetricTrend{
+					"CPU使用率": {Role: RoleCPUUsage, Unit: "%", Series: []TrendSeries{series("b:9100", "", 10), series("a:9100", "", 20)}},
+					"磁盘使用率":  {Role: RoleDiskUsage, Unit: "%", Series: []TrendSeries{series("a:9100", "/", 30)}},
+					"QPS":    {Unit: "req/s", Series: []TrendSeries{series("a:9100", "", 100)}},
+					"查询失败":   {Error: "timeout", Series: []TrendSeries{}},
+				},
+			},
+		},
+	}
+	buildTrendCharts(data)
+
+	group := data.MetricGroups["host"]
+	if len(group.TrendCharts) != 3 {
+		t.Fatalf("期望3张指标趋势图，实际%d张", len(group.TrendCharts))
+	}
+	if chart := group.TrendCharts["QPS"]; chart == nil || chart.Unit != "req/s" || chart.Series[0].Name != "节点=a:9100" {
+		t.Errorf("指标趋势图不正确: %+v", chart)
+	}
+
+	if len(data.HostTrendCharts) != 2 {
+		t.Fatalf("期望2张主机趋势图，实际%d张", len(data.HostTrendCharts))
+	}
+	hostA := data.HostTrendCharts[0]
+	if hostA.Title != "a:9100 资源使用率趋势" || len(hostA.Series) != 2 || hostA.Series[0].Name != "CPU使用率" || hostA.Series[1].Name != "磁盘使用率 /" {
+		t.Errorf("主机趋势图不正确: %+v", hostA)
+	}
+	if len(data.TrendCharts) != 5 {
+		t.Errorf("期望共5张趋势图，实际%d张", len(data.TrendCharts))
+	}
+}
+
+func TestBuildTrendChartsFollowConfigOrder(t *testing.T) {
+	now := time.Now()
+	trend := func(role string) *MetricTrend {
+		labels := []LabelData{{Name: "instance", Alias: "节点", Value: "a:9100"}}
+		return &MetricTrend{Role: role, Unit: "%", Series: []TrendSeries{{Labels: labels, Points: []TrendPoint{{now, 1}}}}}
+	}
+
+	data := &ReportData{
+		GroupOrder: []string{"host"},
+		HostLabels: HostSummaryConfig{}.WithDefaults(),
+		MetricGroups: map[string]*MetricGroup{
+			"host": {
+				Type:        "host",
+				MetricOrder: []string{"内存使用率", "CPU使用率"},
+				TrendOrder:  []string{"负载", "内存使用率", "CPU使用率", "磁盘使用率"},
+				Trends: map[string]*MetricTrend{
+					"CPU使用率": trend(RoleCPUUsage),
+					"内存使用率":  trend(RoleMemUsage),
+					"磁盘使用率":  trend(RoleDiskUsage),
+					"负载":     trend(""),
+				},
+			},
+		},
+	}
+	buildTrendCharts(data)
+
+	want := []string{"内存使用率 趋势", "CPU使用率 趋势", "负载 趋势", "磁盘使用率 趋势"}
+	if len(data.TrendCharts) != len(want)+1 {
+		t.Fatalf("期望%d张趋势图，实际%d张", len(want)+1, len(data.TrendCharts))
+	}
+	for i, title := range want {
+		if data.TrendCharts[i].Title != title {
+			t.Errorf("第%d张趋势图期望 %s，实际 %s", i, title, data.TrendCharts[i].Title)
+		}
+	}
+	host := data.HostTrendCharts[0]
+	if len(host.Series) != 3 || host.Series[0].Name != "内存使用率" || host.Series[1].Name != "CPU使用率" || host.Series[2].Name != "磁盘使用率 " {
+		t.Errorf("主机趋势图中的指标应按配置顺序排列: %+v", host.Series)
+	}
+}
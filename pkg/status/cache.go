@@ -0,0 +1,279 @@
+package status
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/threshold"
+)
+
+// dayResult 指标某一天的判定结果，已结束的自然日按此结构缓存
+type dayResult struct {
+	Status string         `json:"status"`
+	Detail string         `json:"detail"`
+	Series []seriesResult `json:"series,omitempty"`
+}
+
+// seriesResult 按配置标签合并后的一条序列某一天的判定结果
+type seriesResult struct {
+	Key    string   `json:"key"`
+	Labels []string `json:"labels"`
+	Status string   `json:"status"`
+	Detail string   `json:"detail"`
+	Hourly []string `json:"hourly"`
+}
+
+// dayStore 已结束自然日的判定结果存储，key 由 cacheKey 生成
+type dayStore interface {
+	get(date, key string) (dayResult, bool)
+	put(date, key string, result dayResult)
+}
+
+// cacheKey 返回指标缓存的键。指标配置、数据源或判定方式变化后键随之变化，旧的缓存不再命中
+func cacheKey(datasource string, metric config.MetricConfig, statusConfig config.StatusConfig, loc *time.Location) string {
+	content, err := json.Marshal(struct {
+		Datasource       string
+		Metric           config.MetricConfig
+		Rule             threshold.Rule // 生效的阈值规则，判定方式变化时缓存失效
+		Timezone         string
+		Step             config.Duration
+		SeriesMode       string
+		ViolationPercent float64
+	}{datasource, metric, metric.ThresholdRule(), loc.String(), statusConfig.Step, statusConfig.SeriesMode, statusConfig.ViolationPercent})
+	if err != nil {
+		// 无法生成指纹时仅按名称区分，不影响查询
+		return metric.Name
+	}
+	sum := sha256.Sum256(content)
+	return metric.Name + "@" + hex.EncodeToString(sum[:8])
+}
+
+const (
+	cacheFilePrefix = "status_"
+	cacheFileSuffix = ".json"
+)
+
+// fileDayStore 每个自然日一个 JSON 文件，读取时按需加载，flush 时写回有变化的日期
+type fileDayStore struct {
+	dir   string
+	mu    sync.Mutex
+	days  map[string]map[string]dayResult
+	dirty map[string]bool
+}
+
+func newFileDayStore(dir string) *fileDayStore {
+	return &fileDayStore{
+		dir:   dir,
+		days:  make(map[string]map[string]dayResult),
+		dirty: make(map[string]bool),
+	}
+}
+
+func (s *fileDayStore) path(date string) string {
+	return filepath.Join(s.dir, cacheFilePrefix+date+cacheFileSuffix)
+}
+
+// load 返回某一天的缓存，首次访问时从文件读取，调用方需持有锁
+func (s *fileDayStore) load(date string) map[string]dayResult {
+	if results, exists := s.days[date]; exists {
+		return results
+	}
+	results := make(map[string]dayResult)
+	content, err := os.ReadFile(s.path(date))
+	if err == nil {
+		if err := json.Unmarshal(content, &results); err != nil {
+			log.Printf("健康看板缓存 %s 已损坏，重新查询: %v", s.path(date), err)
+			results = make(map[string]dayResult)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("读取健康看板缓存失败: %v", err)
+	}
+	s.days[date] = results
+	return results
+}
+
+func (s *fileDayStore) get(date, key string) (dayResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, exists := s.load(date)[key]
+	return result, exists
+}
+
+func (s *fileDayStore) put(date, key string, result dayResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load(date)[key] = result
+	s.dirty[date] = true
+}
+
+// flush 写回有变化的日期，并删除不在 dates 范围内的缓存文件
+func (s *fileDayStore) flush(dates []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("creating status cache dir: %w", err)
+	}
+	for date := range s.dirty {
+		content, err := json.Marshal(s.days[date])
+		if err != nil {
+			return fmt.Errorf("encoding status cache: %w", err)
+		}
+		// 先写临时文件再重命名，避免读取到写了一半的文件
+		tmp := s.path(date) + ".tmp"
+		if err := os.WriteFile(tmp, content, 0644); err != nil {
+			return fmt.Errorf("writing status cache: %w", err)
+		}
+		if err := os.Rename(tmp, s.path(date)); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("saving status cache: %w", err)
+		}
+		delete(s.dirty, date)
+	}
+
+	keep := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		keep[date] = true
+	}
+	for date := range s.days {
+		if !keep[date] {
+			delete(s.days, date)
+		}
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("reading status cache dir: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, cacheFilePrefix) || !strings.HasSuffix(name, cacheFileSuffix) {
+			continue
+		}
+		date := strings.TrimSuffix(strings.TrimPrefix(name, cacheFilePrefix), cacheFileSuffix)
+		if !keep[date] {
+			os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+	return nil
+}
+
+// Cache 健康看板缓存。已结束的自然日查询一次后持久化保存，当天的状态由后台按 refresh_interval 刷新，
+// 页面直接读取缓存，不再在每次访问时查询 Prometheus
+type Cache struct {
+	resolver  ClientResolver
+	cfg       *config.Config
+	store     *fileDayStore
+	refreshMu sync.Mutex    // 同一时刻只执行一次刷新
+	stop      chan struct{} // 关闭后停止后台刷新
+	stopOnce  sync.Once
+	stopped   bool // 已停止，不再写入缓存文件，由 refreshMu 保护
+
+	mu   sync.RWMutex
+	data *StatusData
+}
+
+// NewCache 创建健康看板缓存，需调用 Start 启动后台刷新
+func NewCache(resolver ClientResolver, cfg *config.Config) *Cache {
+	return &Cache{
+		resolver: resolver,
+		cfg:      cfg,
+		store:    newFileDayStore(cfg.Status.WithDefaults().CacheDir),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start 立即在后台刷新一次，之后按 refresh_interval 定时刷新
+func (c *Cache) Start() {
+	interval := c.cfg.Status.WithDefaults().RefreshInterval.Std()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := c.Refresh(); err != nil {
+				log.Printf("刷新健康看板失败: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("已启动健康看板后台刷新，间隔: %v", interval)
+}
+
+// Stop 停止后台刷新，等待正在进行的刷新及缓存写入完成后返回。重新加载配置时新旧缓存共用 cache_dir，
+// 停止后不再写入缓存文件，避免覆盖新缓存写入的文件
+func (c *Cache) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.refreshMu.Lock()
+	c.stopped = true
+	c.refreshMu.Unlock()
+}
+
+// Refresh 重新收集看板数据：已结束的自然日读取缓存，只查询当天及尚未缓存的日期
+func (c *Cache) Refresh() (*StatusData, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refresh()
+}
+
+// refresh 执行一次刷新，调用方需持有 refreshMu
+func (c *Cache) refresh() (*StatusData, error) {
+	data, err := collectMetricStatus(c.resolver, c.cfg, time.Now(), c.store)
+	if err != nil {
+		return nil, err
+	}
+	// 已停止的缓存仍可能被进行中的请求调用，只返回数据，不写入文件
+	if !c.stopped {
+		if err := c.store.flush(data.Dates); err != nil {
+			// 缓存写入失败只影响下次刷新的查询量
+			log.Printf("保存健康看板缓存失败: %v", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.data = data
+	c.mu.Unlock()
+	return data, nil
+}
+
+// Data 返回最近一次刷新的看板数据，尚未刷新过时同步刷新一次
+func (c *Cache) Data() (*StatusData, error) {
+	c.mu.RLock()
+	data := c.data
+	c.mu.RUnlock()
+	if data != nil {
+		return data, nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	// 等待期间后台刷新可能已经完成
+	c.mu.RLock()
+	data = c.data
+	c.mu.RUnlock()
+	if data != nil {
+		return data, nil
+	}
+	return c.refresh()
+}
+
+// Detail 返回单个指标的下钻数据，date 为热力图显示的日期
+func (c *Cache) Detail(name, date string) (*MetricDetail, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	return metricDetail(data, name, date)
+}
@@ -0,0 +1,198 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/metrics"
+	"PromAI/pkg/threshold"
+)
+
+func TestCollectMetricStatusWithDayStore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type:    "host",
+			Metrics: []config.MetricConfig{{Name: "CPU使用率", Query: "q_cpu", Threshold: 80, Labels: config.Labels{{Name: "instance", Alias: "节点"}}}},
+		}},
+	}
+	cfg.Status.Days = 3
+	cfg.Status.Timezone = "UTC"
+
+	api := &fakeAPI{matrices: map[string]model.Matrix{"q_cpu": {series("a", 10, 95)}}}
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	store := newFileDayStore(dir)
+	data, err := collectMetricStatus(fakeResolver{api}, cfg, now, store)
+	if err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	if len(api.ranges) != 3 {
+		t.Fatalf("首次收集应查询 3 天，实际 %d 次", len(api.ranges))
+	}
+	if err := store.flush(data.Dates); err != nil {
+		t.Fatalf("保存缓存失败: %v", err)
+	}
+	// 当天尚未结束，不写入缓存
+	if _, err := os.Stat(filepath.Join(dir, "status_2025-03-03.json")); !os.IsNotExist(err) {
+		t.Errorf("当天的状态不应缓存: %v", err)
+	}
+
+	// 重启后从文件读取已结束的自然日，只查询当天
+	api.ranges = nil
+	api.matrices["q_cpu"] = model.Matrix{series("a", 10)}
+	data, err = collectMetricStatus(fakeResolver{api}, cfg, now.Add(5*time.Minute), newFileDayStore(dir))
+	if err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	if len(api.ranges) != 1 || !api.ranges[0].Start.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("应只查询当天，实际 %v", api.ranges)
+	}
+	metric := data.Metrics[0]
+	if metric.DailyStatus["2025-03-01"] != threshold.StatusCritical || metric.DailyStatus["2025-03-03"] != threshold.StatusNormal {
+		t.Errorf("缓存的状态不正确: %v", metric.DailyStatus)
+	}
+	if len(metric.Series) != 1 || metric.Series[0].HourlyStatus["2025-03-01"][1] != threshold.StatusCritical {
+		t.Errorf("缓存的序列状态不正确: %+v", metric.Series)
+	}
+	if metric.DailyDetail["2025-03-01"] != `最大值 95.00 {instance="a"}` {
+		t.Errorf("缓存的取值说明不正确: %s", metric.DailyDetail["2025-03-01"])
+	}
+
+	// 修改阈值后缓存不再命中
+	api.ranges = nil
+	cfg.MetricTypes[0].Metrics[0].Threshold = 99
+	if _, err := collectMetricStatus(fakeResolver{api}, cfg, now, newFileDayStore(dir)); err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	if len(api.ranges) != 3 {
+		t.Errorf("阈值变化后应重新查询 3 天，实际 %d 次", len(api.ranges))
+	}
+
+	// 超出统计范围的日期被清理
+	if err := newFileDayStore(dir).flush([]string{"2025-03-02", "2025-03-03"}); err != nil {
+		t.Fatalf("清理缓存失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "status_2025-03-01.json")); !os.IsNotExist(err) {
+		t.Errorf("超出范围的缓存应被删除: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "status_2025-03-02.json")); err != nil {
+		t.Errorf("范围内的缓存不应删除: %v", err)
+	}
+}
+
+// failingResolver 所有指标都没有可用的数据源
+type failingResolver struct{}
+
+func (failingResolver) ClientFor(metricType config.MetricType, metric config.MetricConfig) (string, metrics.PrometheusAPI, error) {
+	return "", nil, errors.New("datasource not found")
+}
+
+func TestCollectMetricStatusSkipsFailedDays(t *testing.T) {
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type:    "host",
+			Metrics: []config.MetricConfig{{Name: "CPU使用率", Query: "q_missing", Threshold: 80}},
+		}},
+	}
+	cfg.Status.Days = 2
+	cfg.Status.Timezone = "UTC"
+
+	store := newFileDayStore(t.TempDir())
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	if _, err := collectMetricStatus(failingResolver{}, cfg, now, store); err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	if _, cached := store.get("2025-03-02", cacheKey("", cfg.MetricTypes[0].Metrics[0], cfg.Status.WithDefaults(), time.UTC)); cached {
+		t.Error("无法查询的日期不应写入缓存")
+	}
+}
+
+// blockingAPI 第一次范围查询阻塞到 release 关闭
+type blockingAPI struct {
+	fakeAPI
+	mu      sync.Mutex
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingAPI) QueryRange(ctx context.Context, query string, r v1.Range, opts ...v1.Option) (model.Value, v1.Warnings, error) {
+	b.once.Do(func() { close(b.started) })
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fakeAPI.QueryRange(ctx, query, r, opts...)
+}
+
+type blockingResolver struct {
+	api *blockingAPI
+}
+
+func (r blockingResolver) ClientFor(metricType config.MetricType, metric config.MetricConfig) (string, metrics.PrometheusAPI, error) {
+	return "default", r.api, nil
+}
+
+func TestCacheStopWaitsForRefresh(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type:    "host",
+			Metrics: []config.MetricConfig{{Name: "CPU使用率", Query: "q_cpu", Threshold: 80, Labels: config.Labels{{Name: "instance", Alias: "节点"}}}},
+		}},
+	}
+	cfg.Status.Days = 3
+	cfg.Status.CacheDir = dir
+
+	api := &blockingAPI{
+		fakeAPI: fakeAPI{matrices: map[string]model.Matrix{"q_cpu": {series("a", 10)}}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	cache := NewCache(blockingResolver{api}, cfg)
+	cache.Start()
+	<-api.started
+
+	stopped := make(chan struct{})
+	go func() {
+		cache.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop 应等待正在进行的刷新完成")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(api.release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("刷新完成后 Stop 应返回")
+	}
+	// Stop 返回时进行中的刷新已写入缓存
+	files, _ := filepath.Glob(filepath.Join(dir, "status_*.json"))
+	if len(files) == 0 {
+		t.Fatal("停止前进行中的刷新应写入缓存")
+	}
+
+	// 停止后的刷新不再写入缓存文件，避免覆盖新缓存写入的文件
+	for _, file := range files {
+		os.Remove(file)
+	}
+	if _, err := cache.Refresh(); err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "status_*.json")); len(files) != 0 {
+		t.Errorf("停止后的刷新不应写入缓存: %v", files)
+	}
+}
@@ -2,11 +2,18 @@ package status
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"PromAI/pkg/config"
 	"PromAI/pkg/metrics"
+	"PromAI/pkg/threshold"
+	"PromAI/pkg/units"
 
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
@@ -33,58 +40,110 @@ type MetricConfig struct {
 	ThresholdType string            `yaml:"threshold_type"`
 }
 
+// ClientResolver 按指标选择数据源客户端，由 metrics.Collector 实现
+type ClientResolver interface {
+	ClientFor(metricType config.MetricType, metric config.MetricConfig) (string, metrics.PrometheusAPI, error)
+}
+
 type StatusSummary struct {
 	Normal       int
 	Warning      int // 新增警告状态计数
-	Abnormal     int
+	Critical     int
 	TotalMetrics int            // 总指标数
 	TypeCounts   map[string]int // 每种类型的指标数量
 }
 
 type MetricStatus struct {
 	Name          string
-	DailyStatus   map[string]string // key是日期，value是状态("normal"/"warning"/"abnormal")
+	DailyStatus   map[string]string // key是日期(2006-01-02)，value是状态("normal"/"warning"/"critical")
+	DailyDetail   map[string]string // key是日期，value是决定当天状态的取值说明，用于提示
 	Threshold     float64
 	Unit          string
 	ThresholdType string
+	ThresholdText string         // 阈值规则说明
+	LabelNames    []string       // 序列表格的列名，为配置的标签别名
+	Series        []SeriesStatus // 按配置的标签区分的各序列每天的状态
+}
+
+// SeriesStatus 单条序列每天的状态，标签值相同的原始序列合并为一条
+type SeriesStatus struct {
+	Labels       []string            // 与 MetricStatus.LabelNames 对应的标签值
+	DailyStatus  map[string]string   // key是日期，当天没有该序列的采样时为空
+	DailyDetail  map[string]string   // key是日期，value是决定当天状态的取值说明
+	HourlyStatus map[string][]string // key是日期，value是当天 0-23 时每小时的状态，没有采样的小时为空
 }
 
+// MetricDetail 单个指标按序列下钻的数据
+type MetricDetail struct {
+	Metric      MetricStatus
+	Dates       []string
+	Timezone    string
+	RefreshedAt time.Time
+	Date        string   // 热力图显示的日期
+	Hours       []string // 热力图的小时列，00-23
+}
+
+// ErrMetricNotFound 配置中没有指定名称的指标
+var ErrMetricNotFound = errors.New("metric not found")
+
+// seriesLabel 未配置 labels 时序列表格的列名
+const seriesLabel = "序列"
+
 type StatusData struct {
-	Summary StatusSummary
-	Metrics []MetricStatus
-	Dates   []string
+	Summary     StatusSummary
+	Metrics     []MetricStatus
+	Dates       []string  // 完整日期，格式为 2006-01-02
+	Timezone    string    // 划分自然日使用的时区
+	RefreshedAt time.Time // 数据刷新时间
 }
 
-func GenerateStatusData(days int) (*StatusData, error) {
-	data := &StatusData{
-		Summary: StatusSummary{
-			TypeCounts: make(map[string]int), // 初始化类型计数map
-		},
-		Metrics: []MetricStatus{},
-		Dates:   make([]string, days),
-	}
+// dateLayout 健康看板使用的日期格式，包含年份以区分跨年的日期
+const dateLayout = "2006-01-02"
 
-	// 生成最近n天的日期
-	now := time.Now()
+// statusDays 返回截至 now 所在自然日的最近 days 天的零点，按时间先后排列
+func statusDays(now time.Time, days int) []time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	result := make([]time.Time, days)
 	for i := 0; i < days; i++ {
-		date := now.AddDate(0, 0, -i)
-		data.Dates[days-1-i] = date.Format("01-02") // MM-DD格式
+		result[days-1-i] = today.AddDate(0, 0, -i)
 	}
+	return result
+}
 
-	return data, nil
+// CollectMetricStatus 按配置的时区、天数和采样间隔收集各指标最近几天的状态，每天都重新查询；
+// 页面展示使用 Cache，已结束的自然日只查询一次
+func CollectMetricStatus(resolver ClientResolver, config *config.Config) (*StatusData, error) {
+	return collectMetricStatus(resolver, config, time.Now(), nil)
 }
 
-func CollectMetricStatus(client metrics.PrometheusAPI, config *config.Config) (*StatusData, error) {
-	data, err := GenerateStatusData(7) // 显示最近7天的数据
+// collectMetricStatus 收集各指标最近几天的状态。store 不为 nil 时，已结束的自然日优先读取缓存，查询成功后写入缓存
+func collectMetricStatus(resolver ClientResolver, cfg *config.Config, now time.Time, store dayStore) (*StatusData, error) {
+	statusConfig := cfg.Status.WithDefaults()
+	loc, err := statusConfig.Location()
 	if err != nil {
-		log.Printf("生成状态数据失败: %v", err)
-		return nil, err
+		log.Printf("健康看板时区配置无效，使用本地时区: %v", err)
+		loc = time.Local
+	}
+	now = now.In(loc)
+	days := statusDays(now, statusConfig.Days)
+
+	data := &StatusData{
+		Summary: StatusSummary{
+			TypeCounts: make(map[string]int), // 初始化类型计数map
+		},
+		Metrics:     []MetricStatus{},
+		Dates:       make([]string, len(days)),
+		Timezone:    loc.String(),
+		RefreshedAt: now,
+	}
+	for i, day := range days {
+		data.Dates[i] = day.Format(dateLayout)
 	}
 
 	log.Printf("开始收集指标状态数据，时间范围: %v", data.Dates)
 
 	// 遍历所有指标类型
-	for _, metricType := range config.MetricTypes {
+	for _, metricType := range cfg.MetricTypes {
 		log.Printf("处理指标类型: %s", metricType.Type)
 
 		// 统计每种类型的指标数量
@@ -100,40 +159,90 @@ func CollectMetricStatus(client metrics.PrometheusAPI, config *config.Config) (*
 			metricStatus := MetricStatus{
 				Name:          metric.Name,
 				DailyStatus:   make(map[string]string),
+				DailyDetail:   make(map[string]string),
 				Threshold:     metric.Threshold,
 				Unit:          metric.Unit,
 				ThresholdType: metric.ThresholdType,
+				ThresholdText: metric.ThresholdRule().Describe(metric.Unit),
+				LabelNames:    labelNames(metric),
+			}
+			seriesIndex := make(map[string]int)
+
+			datasource, client, clientErr := resolver.ClientFor(metricType, metric)
+			if clientErr != nil {
+				log.Printf("指标 [%s] 无法查询: %v", metric.Name, clientErr)
 			}
+			key := cacheKey(datasource, metric, statusConfig, loc)
+
+			// 查询每天的状态，当天只统计到当前时刻
+			for i, day := range days {
+				date := data.Dates[i]
+				end := day.AddDate(0, 0, 1).Add(-time.Second)
+				if end.After(now) {
+					end = now
+				}
 
-			// 查询每天的状态
-			for _, date := range data.Dates {
-				status, err := queryMetricStatus(client, metric, date)
-				if err != nil {
-					log.Printf("查询指标 [%s] 在 %s 的状态失败: %v", metric.Name, date, err)
-					metricStatus.DailyStatus[date] = "abnormal"
-					data.Summary.Abnormal++
-				} else {
-					metricStatus.DailyStatus[date] = status
-					switch status {
-					case "normal":
-						log.Printf("指标 [%s] 在 %s 状态正常", metric.Name, date)
-						data.Summary.Normal++
-					case "warning":
-						log.Printf("指标 [%s] 在 %s 状态警告", metric.Name, date)
-						data.Summary.Warning++
-					case "abnormal":
-						log.Printf("指标 [%s] 在 %s 状态异常", metric.Name, date)
-						data.Summary.Abnormal++
+				// 已结束的自然日结果不会再变化，命中缓存时不再查询
+				finished := !day.AddDate(0, 0, 1).After(now)
+				result, cached := dayResult{}, false
+				if store != nil && finished {
+					result, cached = store.get(date, key)
+				}
+				if !cached {
+					var err error
+					if client == nil {
+						result, err = failedDay(clientErr), clientErr
+					} else {
+						result, err = queryDay(client, metric, day, end, statusConfig)
+					}
+					if err != nil {
+						log.Printf("查询指标 [%s] 在 %s 的状态失败: %v", metric.Name, date, err)
+					} else if store != nil && finished {
+						store.put(date, key, result)
 					}
 				}
+
+				for _, sr := range result.Series {
+					i, exists := seriesIndex[sr.Key]
+					if !exists {
+						i = len(metricStatus.Series)
+						seriesIndex[sr.Key] = i
+						metricStatus.Series = append(metricStatus.Series, SeriesStatus{
+							Labels:       sr.Labels,
+							DailyStatus:  make(map[string]string),
+							DailyDetail:  make(map[string]string),
+							HourlyStatus: make(map[string][]string),
+						})
+					}
+					series := &metricStatus.Series[i]
+					series.DailyStatus[date] = sr.Status
+					series.DailyDetail[date] = sr.Detail
+					series.HourlyStatus[date] = sr.Hourly
+				}
+				metricStatus.DailyStatus[date] = result.Status
+				metricStatus.DailyDetail[date] = result.Detail
+				switch result.Status {
+				case threshold.StatusNormal:
+					log.Printf("指标 [%s] 在 %s 状态正常", metric.Name, date)
+					data.Summary.Normal++
+				case threshold.StatusWarning:
+					log.Printf("指标 [%s] 在 %s 状态警告", metric.Name, date)
+					data.Summary.Warning++
+				default:
+					log.Printf("指标 [%s] 在 %s 状态异常", metric.Name, date)
+					data.Summary.Critical++
+				}
 			}
 
+			sort.SliceStable(metricStatus.Series, func(i, j int) bool {
+				return strings.Join(metricStatus.Series[i].Labels, "\x00") < strings.Join(metricStatus.Series[j].Labels, "\x00")
+			})
 			data.Metrics = append(data.Metrics, metricStatus)
 		}
 	}
 
 	log.Printf("状态数据收集完成. 总指标数: %d, 正常: %d, 警告: %d, 异常: %d",
-		data.Summary.TotalMetrics, data.Summary.Normal, data.Summary.Warning, data.Summary.Abnormal)
+		data.Summary.TotalMetrics, data.Summary.Normal, data.Summary.Warning, data.Summary.Critical)
 
 	// 打印每种类型的指标数量
 	for typeName, count := range data.Summary.TypeCounts {
@@ -143,17 +252,66 @@ func CollectMetricStatus(client metrics.PrometheusAPI, config *config.Config) (*
 	return data, nil
 }
 
-func queryMetricStatus(client metrics.PrometheusAPI, metric config.MetricConfig, date string) (string, error) {
-	ctx := context.Background()
+// metricDetail 从看板数据中取出单个指标的下钻数据。date 为热力图显示的日期，为空或不在统计范围内时取最近一天
+func metricDetail(data *StatusData, name, date string) (*MetricDetail, error) {
+	for _, metric := range data.Metrics {
+		if metric.Name != name {
+			continue
+		}
+		detail := &MetricDetail{
+			Metric:      metric,
+			Dates:       data.Dates,
+			Timezone:    data.Timezone,
+			RefreshedAt: data.RefreshedAt,
+			Date:        data.Dates[len(data.Dates)-1],
+			Hours:       make([]string, 24),
+		}
+		for _, d := range data.Dates {
+			if d == date {
+				detail.Date = date
+			}
+		}
+		for hour := range detail.Hours {
+			detail.Hours[hour] = fmt.Sprintf("%02d", hour)
+		}
+		return detail, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+}
 
-	dateTime, err := time.Parse("01-02", date)
+// queryDay 查询指标某一天的状态，转换为可缓存的结果
+func queryDay(client metrics.PrometheusAPI, metric config.MetricConfig, start, end time.Time, statusConfig config.StatusConfig) (dayResult, error) {
+	verdict, seriesDays, err := queryMetricStatus(client, metric, start, end, statusConfig)
 	if err != nil {
-		return "abnormal", err
+		return failedDay(err), err
+	}
+	result := dayResult{
+		Status: verdict.Status,
+		Detail: verdict.describe(metric),
+		Series: make([]seriesResult, 0, len(seriesDays)),
 	}
+	for _, sd := range seriesDays {
+		result.Series = append(result.Series, seriesResult{
+			Key:    sd.key,
+			Labels: sd.labels,
+			Status: sd.verdict.Status,
+			Detail: sd.verdict.describe(metric),
+			Hourly: sd.hourly,
+		})
+	}
+	return result, nil
+}
 
-	// 设置查询时间范围为那一天的0点到23:59:59
-	startTime := time.Date(time.Now().Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, time.Local)
-	endTime := startTime.Add(24 * time.Hour).Add(-time.Second)
+// failedDay 查询失败的一天判为严重
+func failedDay(err error) dayResult {
+	return dayResult{Status: threshold.StatusCritical, Detail: fmt.Sprintf("查询失败: %v", err)}
+}
+
+// queryMetricStatus 查询指标在 [start, end] 内的采样，逐条序列按各自的阈值规则判定，取最严重的序列状态。
+// 同时返回按配置标签合并后的各序列状态及每小时状态，小时按 start 所在时区划分
+func queryMetricStatus(client metrics.PrometheusAPI, metric config.MetricConfig, start, end time.Time, statusConfig config.StatusConfig) (dayVerdict, []seriesDay, error) {
+	ctx := context.Background()
+	step := statusConfig.Step.Std()
 
 	log.Printf(`
 查询指标: [%s]
@@ -165,130 +323,333 @@ PromQL: %s
 3. 设置时间范围为: %s 到 %s
 -------------------`,
 		metric.Name,
-		startTime.Format("2006-01-02 15:04:05"),
-		endTime.Format("2006-01-02 15:04:05"),
+		start.Format("2006-01-02 15:04:05 MST"),
+		end.Format("2006-01-02 15:04:05 MST"),
 		metric.Query,
 		metric.Query,
-		startTime.Format("2006-01-02 15:04:05"),
-		endTime.Format("2006-01-02 15:04:05"))
+		start.Format("2006-01-02 15:04:05 MST"),
+		end.Format("2006-01-02 15:04:05 MST"))
 
-	// 直接使用原始查询语句
 	result, _, err := client.QueryRange(ctx, metric.Query, v1.Range{
-		Start: startTime,
-		End:   endTime,
-		Step:  time.Hour, // 每小时一个采样点
+		Start: start,
+		End:   end,
+		Step:  step,
 	})
-
 	if err != nil {
 		log.Printf("执行查询失败 [%s]: %v", metric.Query, err)
-		return "abnormal", err
+		return dayVerdict{seriesVerdict: seriesVerdict{Status: threshold.StatusCritical}}, nil, err
 	}
 
 	switch v := result.(type) {
 	case model.Matrix:
 		if len(v) == 0 {
 			log.Printf("指标 [%s] 查询结果为空", metric.Name)
-			return "abnormal", nil
+			return dayVerdict{seriesVerdict: seriesVerdict{Status: threshold.StatusCritical}, Empty: true}, nil, nil
 		}
 
 		log.Printf("指标 [%s] 返回 %d 个时间序列", metric.Name, len(v))
 
-		maxValue := float64(0)
-		// 遍历每个时间序列
-		for _, series := range v {
-			// 遍历每个采样点，找出最大值
-			for _, sample := range series.Values {
-				value := float64(sample.Value)
-				if value > maxValue {
-					maxValue = value
+		// 按当天结束时刻的阈值判定，标签覆盖和 threshold_query 与报告一致
+		rules := metrics.ThresholdRules(ctx, client, metric, end)
+		direction := metric.ThresholdRule().Direction()
+		day := dayVerdict{seriesVerdict: seriesVerdict{Status: threshold.StatusNormal}}
+		var seriesDays []seriesDay
+		seriesIndex := make(map[string]int)
+		for i, series := range v {
+			labels := make(map[string]string, len(series.Metric))
+			for name, value := range series.Metric {
+				labels[string(name)] = string(value)
+			}
+			rule, source := rules.For(labels)
+			verdict := dayVerdict{
+				seriesVerdict: evaluateSeries(series.Values, metric, rule, statusConfig),
+				Series:        seriesName(series.Metric),
+			}
+			log.Printf("指标 [%s] 序列 %s 采样点: %d, 阈值规则: %s, 取值: %s %v, 状态: %s",
+				metric.Name, series.Metric, len(series.Values), source, verdict.Method, verdict.Value, verdict.Status)
+			if i == 0 || verdict.moreSevere(day.seriesVerdict, direction) {
+				day = verdict
+			}
+
+			// 标签值相同的序列合并，取更严重的结果
+			key, values := seriesKey(metric, series.Metric)
+			hourly := hourlyStatus(series.Values, metric, rule, statusConfig, start.Location())
+			j, exists := seriesIndex[key]
+			if !exists {
+				seriesIndex[key] = len(seriesDays)
+				seriesDays = append(seriesDays, seriesDay{key: key, labels: values, verdict: verdict, hourly: hourly})
+				continue
+			}
+			merged := &seriesDays[j]
+			if verdict.moreSevere(merged.verdict.seriesVerdict, direction) {
+				merged.verdict = verdict
+			}
+			for hour, status := range hourly {
+				switch {
+				case merged.hourly[hour] == "":
+					merged.hourly[hour] = status
+				case status != "":
+					merged.hourly[hour] = threshold.Worse(merged.hourly[hour], status)
 				}
-				log.Printf("指标 [%s] 时间: %v, 值: %v",
-					metric.Name,
-					sample.Timestamp.Time().Format("15:04:05"),
-					value)
 			}
 		}
+		return day, seriesDays, nil
 
-		// 使用最大值进行阈值判断
-		status := checkThreshold(maxValue, metric.Threshold, metric.ThresholdType)
-		log.Printf("指标 [%s] 最大值: %v, 阈值: %v, 阈值类型: %s, 状态: %s",
-			metric.Name,
-			maxValue,
-			metric.Threshold,
-			metric.ThresholdType,
-			status)
+	default:
+		log.Printf("指标 [%s] 返回了意外的结果类型: %T", metric.Name, result)
+		return dayVerdict{seriesVerdict: seriesVerdict{Status: threshold.StatusCritical}}, nil, nil
+	}
+}
+
+// 单条序列一天内的取值方式
+const (
+	aggregationMin       = "min"
+	aggregationMax       = "max"
+	aggregationAvg       = "avg"
+	aggregationWorst     = "worst"     // 逐个采样点判定，取最严重的采样点
+	aggregationViolation = "violation" // violation 模式，取超出阈值的时间占比
+)
+
+// aggregationNames 取值方式在提示中的名称
+var aggregationNames = map[string]string{
+	aggregationMin:   "最小值",
+	aggregationMax:   "最大值",
+	aggregationAvg:   "平均值",
+	aggregationWorst: "最差采样值",
+}
 
-		return status, nil
+// seriesVerdict 单条序列的判定结果及决定结果的取值
+type seriesVerdict struct {
+	Status   string
+	Value    float64
+	Method   string // 取值方式：min、max、avg、pNN、worst 或 violation
+	HasValue bool   // 序列没有有效采样点时为 false
+}
+
+// dayVerdict 某一天的判定结果，来自状态最严重的序列
+type dayVerdict struct {
+	seriesVerdict
+	Series string // 决定当天状态的序列标签
+	Empty  bool   // 查询结果为空
+}
+
+// moreSevere 判断 v 是否比 other 更严重：先比较状态，状态相同时按阈值方向比较取值
+func (v seriesVerdict) moreSevere(other seriesVerdict, direction string) bool {
+	if v.Status != other.Status {
+		return threshold.Worse(v.Status, other.Status) == v.Status
+	}
+	if !v.HasValue || !other.HasValue || v.Method != other.Method {
+		return v.HasValue && !other.HasValue
+	}
+	if v.Method == aggregationViolation {
+		return v.Value > other.Value
+	}
+	switch direction {
+	case threshold.DirectionUpper:
+		return v.Value > other.Value
+	case threshold.DirectionLower:
+		return v.Value < other.Value
+	}
+	return false
+}
 
+// describe 生成提示中显示的取值说明，如“最大值 92.30% {instance="db-01"}”
+func (d dayVerdict) describe(metric config.MetricConfig) string {
+	var text string
+	switch {
+	case d.Empty:
+		return "查询结果为空"
+	case !d.HasValue:
+		return "无有效采样点"
+	case d.Method == aggregationViolation:
+		text = fmt.Sprintf("超限时间占比 %.1f%%", d.Value)
 	default:
-		log.Printf("指标 [%s] 返回了意外的结果类型: %T", metric.Name, result)
-		return "abnormal", nil
+		name, ok := aggregationNames[d.Method]
+		if !ok {
+			name = strings.ToUpper(d.Method)
+		}
+		text = name + " " + units.Format(d.Value, metric.Unit, metric.TargetUnit, metric.FormatType)
+	}
+	if d.Series != "" {
+		text += " " + d.Series
+	}
+	return text
+}
+
+// seriesDay 按配置标签合并后的一条序列某一天的判定结果
+type seriesDay struct {
+	key     string
+	labels  []string
+	verdict dayVerdict
+	hourly  []string
+}
+
+// labelNames 返回序列表格的列名：配置的标签别名，未配置 labels 时为完整的序列标签
+func labelNames(metric config.MetricConfig) []string {
+	if len(metric.Labels) == 0 {
+		return []string{seriesLabel}
+	}
+	names := make([]string, 0, len(metric.Labels))
+	for _, label := range metric.Labels {
+		names = append(names, label.Alias)
+	}
+	return names
+}
+
+// seriesKey 按配置的标签返回序列标识及各标签值，缺失的标签显示为 "-"
+func seriesKey(metric config.MetricConfig, labels model.Metric) (string, []string) {
+	if len(metric.Labels) == 0 {
+		name := seriesName(labels)
+		return name, []string{name}
+	}
+	values := make([]string, 0, len(metric.Labels))
+	for _, label := range metric.Labels {
+		value := string(labels[model.LabelName(label.Name)])
+		if value == "" {
+			value = "-"
+		}
+		values = append(values, value)
 	}
+	return strings.Join(values, "\x00"), values
 }
 
-// 根据阈值类型判断状态
-func checkThreshold(value, threshold float64, thresholdType string) string {
-	if thresholdType == "" {
-		thresholdType = "greater" // 默认值
+// hourlyStatus 按 loc 时区的小时划分采样点，逐小时判定状态，没有有效采样的小时为空
+func hourlyStatus(samples []model.SamplePair, metric config.MetricConfig, rule threshold.Rule, statusConfig config.StatusConfig, loc *time.Location) []string {
+	buckets := make([][]model.SamplePair, 24)
+	for _, sample := range samples {
+		hour := sample.Timestamp.Time().In(loc).Hour()
+		buckets[hour] = append(buckets[hour], sample)
 	}
+	hourly := make([]string, 24)
+	for hour, bucket := range buckets {
+		if verdict := evaluateSeries(bucket, metric, rule, statusConfig); verdict.HasValue {
+			hourly[hour] = verdict.Status
+		}
+	}
+	return hourly
+}
 
-	// 警告阈值为正常阈值的90%
-	warningFactor := 0.9
+// seriesName 返回去掉指标名后的序列标签
+func seriesName(metric model.Metric) string {
+	labels := metric.Clone()
+	delete(labels, model.MetricNameLabel)
+	if len(labels) == 0 {
+		return ""
+	}
+	return labels.String()
+}
+
+// statusAggregation 返回单条序列一天内的取值方式。显式配置的 status_aggregation 优先；
+// 未配置时 violation 模式按时间占比判定，否则上限阈值取最大值、下限阈值（less 类、at_least）取最小值，双侧阈值逐点判定
+func statusAggregation(metric config.MetricConfig, rule threshold.Rule, statusConfig config.StatusConfig) string {
+	if metric.StatusAggregation != "" {
+		aggregation := strings.ToLower(metric.StatusAggregation)
+		err := config.ValidateStatusAggregation(aggregation)
+		if err == nil {
+			return aggregation
+		}
+		log.Printf("指标 [%s] 的 status_aggregation 无效，按阈值方向取值: %v", metric.Name, err)
+	}
+	if statusConfig.SeriesMode == config.SeriesModeViolation {
+		return aggregationViolation
+	}
+	switch rule.Direction() {
+	case threshold.DirectionUpper:
+		return aggregationMax
+	case threshold.DirectionLower:
+		return aggregationMin
+	}
+	return aggregationWorst
+}
 
-	switch thresholdType {
-	case "greater":
-		// 当值大于阈值时告警
-		// 例如：CPU使用率 > 80% 告警
-		if value > threshold {
-			return "abnormal"
-		} else if value > threshold*warningFactor {
-			return "warning"
+// aggregate 按取值方式汇总采样值，values 不能为空
+func aggregate(values []float64, aggregation string) float64 {
+	switch aggregation {
+	case aggregationMin:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Min(result, value)
 		}
-		return "normal"
-	case "greater_equal":
-		// 当值大于等于阈值时告警
-		if value >= threshold {
-			return "abnormal"
-		} else if value >= threshold*warningFactor {
-			return "warning"
+		return result
+	case aggregationMax:
+		result := values[0]
+		for _, value := range values[1:] {
+			result = math.Max(result, value)
 		}
-		return "normal"
-	case "less":
-		// 当值小于阈值时告警
-		// 例如：可用节点数 < 3 告警
-		if value < threshold {
-			return "abnormal"
-		} else if value < threshold/warningFactor {
-			return "warning"
+		return result
+	case aggregationAvg:
+		var sum float64
+		for _, value := range values {
+			sum += value
 		}
-		return "normal"
-	case "less_equal":
-		// 当值小于等于阈值时告警
-		if value <= threshold {
-			return "abnormal"
-		} else if value <= threshold/warningFactor {
-			return "warning"
+		return sum / float64(len(values))
+	}
+
+	// 百分位按排序后相邻两点线性插值
+	q, _ := config.ParsePercentile(aggregation)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := q / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// evaluateSeries 判定单条序列在一天内的状态。min/max/avg/pNN 先汇总采样值再按阈值判定；
+// worst 逐点判定取最严重的采样点；violation 按超出阈值的采样点占比判定，
+// 严重占比达到 violation_percent 为严重，警告及以上占比达到时为警告
+func evaluateSeries(samples []model.SamplePair, metric config.MetricConfig, rule threshold.Rule, statusConfig config.StatusConfig) seriesVerdict {
+	values := make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		value := float64(sample.Value)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
 		}
-		return "normal"
-	case "equal":
-		// 值必须等于阈值才正常
-		if value == threshold {
-			return "normal"
+		// 与报告一致，阈值按缩放后的值判断
+		values = append(values, units.Scale(value, metric.ScaleFactor))
+	}
+
+	aggregation := statusAggregation(metric, rule, statusConfig)
+	verdict := seriesVerdict{Status: threshold.StatusNormal, Method: aggregation}
+	if len(values) == 0 {
+		return verdict
+	}
+	verdict.HasValue = true
+
+	switch aggregation {
+	case aggregationWorst:
+		for i, value := range values {
+			status := rule.Evaluate(value)
+			if i == 0 || threshold.Worse(verdict.Status, status) != verdict.Status {
+				verdict.Status, verdict.Value = status, value
+			}
 		}
-		return "abnormal"
-	case "not_equal":
-		// 值不等于阈值才正常
-		if value != threshold {
-			return "normal"
+		return verdict
+
+	case aggregationViolation:
+		var warning, critical int
+		for _, value := range values {
+			switch rule.Evaluate(value) {
+			case threshold.StatusWarning:
+				warning++
+			case threshold.StatusCritical:
+				critical++
+			}
 		}
-		return "abnormal"
-	default:
-		// 默认情况：大于阈值告警
-		if value > threshold {
-			return "abnormal"
-		} else if value > threshold*warningFactor {
-			return "warning"
+		total := float64(len(values))
+		limit := statusConfig.ViolationPercent / 100 * total
+		verdict.Value = float64(warning+critical) / total * 100
+		switch {
+		case critical > 0 && float64(critical) >= limit:
+			verdict.Status = threshold.StatusCritical
+			verdict.Value = float64(critical) / total * 100
+		case warning+critical > 0 && float64(warning+critical) >= limit:
+			verdict.Status = threshold.StatusWarning
 		}
-		return "normal"
+		return verdict
 	}
+
+	verdict.Value = aggregate(values, aggregation)
+	verdict.Status = rule.Evaluate(verdict.Value)
+	return verdict
 }
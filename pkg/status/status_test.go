@@ -0,0 +1,339 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"PromAI/pkg/config"
+	"PromAI/pkg/metrics"
+	"PromAI/pkg/threshold"
+)
+
+// fakeAPI 按查询语句返回固定的矩阵，并记录范围查询的时间窗口
+type fakeAPI struct {
+	matrices map[string]model.Matrix
+	ranges   []v1.Range
+}
+
+func (f *fakeAPI) Query(ctx context.Context, query string, ts time.Time, opts ...v1.Option) (model.Value, v1.Warnings, error) {
+	return model.Vector{}, nil, nil
+}
+
+func (f *fakeAPI) QueryRange(ctx context.Context, query string, r v1.Range, opts ...v1.Option) (model.Value, v1.Warnings, error) {
+	f.ranges = append(f.ranges, r)
+	return f.matrices[query], nil, nil
+}
+
+type fakeResolver struct {
+	api *fakeAPI
+}
+
+func (r fakeResolver) ClientFor(metricType config.MetricType, metric config.MetricConfig) (string, metrics.PrometheusAPI, error) {
+	return "default", r.api, nil
+}
+
+// series 生成一条采样值依次为 values 的序列
+func series(instance string, values ...float64) *model.SampleStream {
+	stream := &model.SampleStream{Metric: model.Metric{"instance": model.LabelValue(instance)}}
+	for i, value := range values {
+		stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.Time(i * 3600 * 1000), Value: model.SampleValue(value)})
+	}
+	return stream
+}
+
+func TestCollectMetricStatusAcrossYearBoundary(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type:    "host",
+			Metrics: []config.MetricConfig{{Name: "CPU使用率", Query: "q_cpu", Threshold: 80}},
+		}},
+	}
+	cfg.Status.Days = 3
+	cfg.Status.Timezone = "Asia/Shanghai"
+	cfg.Status.Step = config.Duration(30 * time.Minute)
+
+	api := &fakeAPI{matrices: map[string]model.Matrix{"q_cpu": {series("a", 10)}}}
+	// UTC 时间仍是 1 月 1 日，上海已经是 1 月 2 日
+	now := time.Date(2025, 1, 1, 18, 30, 0, 0, time.UTC)
+
+	data, err := collectMetricStatus(fakeResolver{api}, cfg, now, nil)
+	if err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+
+	wantDates := []string{"2024-12-31", "2025-01-01", "2025-01-02"}
+	for i, want := range wantDates {
+		if data.Dates[i] != want {
+			t.Errorf("Dates[%d] = %s, 期望 %s", i, data.Dates[i], want)
+		}
+	}
+	if data.Timezone != "Asia/Shanghai" {
+		t.Errorf("时区不正确: %s", data.Timezone)
+	}
+
+	if len(api.ranges) != 3 {
+		t.Fatalf("期望3次范围查询，实际%d次", len(api.ranges))
+	}
+	first := api.ranges[0]
+	if !first.Start.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, loc)) || !first.End.Equal(time.Date(2024, 12, 31, 23, 59, 59, 0, loc)) {
+		t.Errorf("第一天的查询窗口不正确: %v ~ %v", first.Start, first.End)
+	}
+	if first.Step != 30*time.Minute {
+		t.Errorf("采样间隔不正确: %v", first.Step)
+	}
+	// 当天只查询到当前时刻，不会查询未来
+	last := api.ranges[2]
+	if !last.Start.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, loc)) || !last.End.Equal(now) {
+		t.Errorf("当天的查询窗口不正确: %v ~ %v", last.Start, last.End)
+	}
+	if data.Metrics[0].DailyStatus["2024-12-31"] != threshold.StatusNormal {
+		t.Errorf("状态不正确: %v", data.Metrics[0].DailyStatus)
+	}
+}
+
+func TestEvaluateSeries(t *testing.T) {
+	rule := threshold.Rule{Type: threshold.Greater, Threshold: 80}
+	samples := series("a", 10, 10, 10, 10, 10, 10, 10, 10, 10, 95).Values
+
+	worst := config.StatusConfig{}.WithDefaults()
+	if got := evaluateSeries(samples, config.MetricConfig{}, rule, worst); got.Status != threshold.StatusCritical {
+		t.Errorf("worst 模式下任一采样点严重即为严重，实际 %s", got.Status)
+	}
+
+	violation := config.StatusConfig{SeriesMode: config.SeriesModeViolation, ViolationPercent: 20}.WithDefaults()
+	if got := evaluateSeries(samples, config.MetricConfig{}, rule, violation); got.Status != threshold.StatusNormal {
+		t.Errorf("超限时间占比 10%% 未达到 20%%，应为正常，实际 %s", got.Status)
+	}
+	violation.ViolationPercent = 10
+	if got := evaluateSeries(samples, config.MetricConfig{}, rule, violation); got.Status != threshold.StatusCritical {
+		t.Errorf("超限时间占比达到 10%%，应为严重，实际 %s", got.Status)
+	}
+
+	// 阈值按缩放后的值判断
+	scale := 100.0
+	if got := evaluateSeries(series("a", 0.5).Values, config.MetricConfig{ScaleFactor: &scale}, rule, worst); got.Status != threshold.StatusNormal {
+		t.Errorf("缩放后 50 应为正常，实际 %s", got.Status)
+	}
+}
+
+func TestQueryMetricStatusPerSeries(t *testing.T) {
+	override := 95.0
+	metric := config.MetricConfig{
+		Name:      "内存使用率",
+		Query:     "q_mem",
+		Threshold: 60,
+		ThresholdOverrides: []threshold.Override{
+			{Match: `instance=~"db-.*"`, Threshold: &override},
+		},
+	}
+	// 数据库主机 70% 在覆盖阈值内，其他主机也正常；旧逻辑会取所有序列的最大值 70 与 60 比较
+	api := &fakeAPI{matrices: map[string]model.Matrix{"q_mem": {
+		series("db-01", 70, 70),
+		series("app-01", 40, 45),
+	}}}
+
+	now := time.Now()
+	status, _, err := queryMetricStatus(api, metric, now.Add(-time.Hour), now, config.StatusConfig{}.WithDefaults())
+	if err != nil {
+		t.Fatalf("查询状态失败: %v", err)
+	}
+	if status.Status != threshold.StatusNormal {
+		t.Errorf("逐序列判定应为正常，实际 %s", status.Status)
+	}
+
+	api.matrices["q_mem"] = append(api.matrices["q_mem"], series("app-02", 85))
+	status, _, _ = queryMetricStatus(api, metric, now.Add(-time.Hour), now, config.StatusConfig{}.WithDefaults())
+	if status.Status != threshold.StatusCritical {
+		t.Errorf("任一序列严重时应为严重，实际 %s", status.Status)
+	}
+	if status.Series != `{instance="app-02"}` || status.Value != 85 {
+		t.Errorf("应由 app-02 的最大值决定当天状态，实际 %s %v", status.Series, status.Value)
+	}
+}
+
+func TestEvaluateSeriesAggregation(t *testing.T) {
+	worst := config.StatusConfig{}.WithDefaults()
+
+	// at_least 取最小值：可用副本数一天内跌到 1 应为严重
+	warning, critical := 3.0, 2.0
+	replicas := threshold.Rule{Type: threshold.AtLeast, Warning: &warning, Critical: &critical}
+	got := evaluateSeries(series("a", 5, 5, 1, 5).Values, config.MetricConfig{}, replicas, worst)
+	if got.Method != aggregationMin || got.Value != 1 || got.Status != threshold.StatusCritical {
+		t.Errorf("at_least 应取最小值 1 判为严重，实际 %+v", got)
+	}
+
+	// less 与 at_least 一样取最小值
+	lessWarning, lessCritical := 80.0, 90.0
+	latency := threshold.Rule{Type: threshold.Less, Warning: &lessWarning, Critical: &lessCritical}
+	got = evaluateSeries(series("a", 10, 95, 20).Values, config.MetricConfig{}, latency, worst)
+	if got.Method != aggregationMin || got.Value != 10 || got.Status != threshold.StatusNormal {
+		t.Errorf("less 应取最小值 10 判为正常，实际 %+v", got)
+	}
+	// 需要按峰值判定时显式配置 status_aggregation
+	got = evaluateSeries(series("a", 10, 95, 20).Values, config.MetricConfig{StatusAggregation: "max"}, latency, worst)
+	if got.Method != aggregationMax || got.Value != 95 || got.Status != threshold.StatusCritical {
+		t.Errorf("显式配置 max 时应取最大值 95 判为严重，实际 %+v", got)
+	}
+
+	// 全为负值时取最大值 -5，而不是从 0 开始比较
+	negativeWarning, negativeCritical := -4.0, -3.0
+	negative := threshold.Rule{Type: threshold.Greater, Warning: &negativeWarning, Critical: &negativeCritical}
+	got = evaluateSeries(series("a", -10, -5, -8).Values, config.MetricConfig{}, negative, worst)
+	if got.Method != aggregationMax || got.Value != -5 || got.Status != threshold.StatusNormal {
+		t.Errorf("应取最大值 -5 判为正常，实际 %+v", got)
+	}
+
+	// 显式配置的百分位优先，偶发的尖峰不影响结果
+	rule := threshold.Rule{Type: threshold.Greater, Threshold: 80}
+	samples := series("a", 10, 20, 30, 40, 50, 60, 70, 80, 90, 99).Values
+	got = evaluateSeries(samples, config.MetricConfig{StatusAggregation: "p50"}, rule, worst)
+	if got.Method != "p50" || got.Value != 55 || got.Status != threshold.StatusNormal {
+		t.Errorf("p50 应为 55 且正常，实际 %+v", got)
+	}
+	got = evaluateSeries(samples, config.MetricConfig{StatusAggregation: "avg"}, rule, worst)
+	if got.Value != 54.9 {
+		t.Errorf("平均值应为 54.9，实际 %v", got.Value)
+	}
+
+	// 无效的取值方式按阈值方向取值
+	got = evaluateSeries(samples, config.MetricConfig{StatusAggregation: "median"}, rule, worst)
+	if got.Method != aggregationMax {
+		t.Errorf("无效的 status_aggregation 应回退为 max，实际 %s", got.Method)
+	}
+
+	// 双侧阈值逐点判定，取最严重的采样点
+	band := threshold.Rule{Type: threshold.Between, Range: &threshold.Range{Min: 40, Max: 60}}
+	got = evaluateSeries(series("a", 50, 10, 55).Values, config.MetricConfig{}, band, worst)
+	if got.Method != aggregationWorst || got.Value != 10 || got.Status != threshold.StatusCritical {
+		t.Errorf("between 应取超出区间的采样点 10，实际 %+v", got)
+	}
+}
+
+func TestDayVerdictDescribe(t *testing.T) {
+	metric := config.MetricConfig{Unit: "%"}
+	day := dayVerdict{
+		seriesVerdict: seriesVerdict{Status: threshold.StatusCritical, Value: 92.3, Method: aggregationMax, HasValue: true},
+		Series:        `{instance="db-01"}`,
+	}
+	if got := day.describe(metric); got != `最大值 92.30% {instance="db-01"}` {
+		t.Errorf("取值说明不正确: %s", got)
+	}
+
+	day.Method, day.Value = aggregationViolation, 15
+	if got := day.describe(metric); got != `超限时间占比 15.0% {instance="db-01"}` {
+		t.Errorf("超限时间占比说明不正确: %s", got)
+	}
+}
+
+func TestCollectMetricDetailPerSeries(t *testing.T) {
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type: "k8s",
+			Metrics: []config.MetricConfig{
+				{Name: "CPU使用率", Query: "q_cpu", Threshold: 80},
+				{
+					Name:      "PVC使用率",
+					Query:     "q_pvc",
+					Threshold: 80,
+					Labels:    config.Labels{{Name: "namespace", Alias: "命名空间"}, {Name: "persistentvolumeclaim", Alias: "PVC"}},
+				},
+			},
+		}},
+	}
+	cfg.Status.Days = 2
+	cfg.Status.Timezone = "UTC"
+
+	pvc := func(namespace, claim, pod string, values ...float64) *model.SampleStream {
+		stream := series(pod, values...)
+		stream.Metric["namespace"] = model.LabelValue(namespace)
+		stream.Metric["persistentvolumeclaim"] = model.LabelValue(claim)
+		return stream
+	}
+	api := &fakeAPI{matrices: map[string]model.Matrix{"q_pvc": {
+		pvc("prod", "data-mysql-0", "mysql-0", 50, 95, 60),
+		pvc("prod", "data-redis-0", "redis-0", 30, 30),
+		// 同一个 PVC 被两个 Pod 挂载时合并为一条，取更严重的结果
+		pvc("prod", "data-redis-0", "redis-1", 40, 85),
+	}}}
+
+	now := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	data, err := collectMetricStatus(fakeResolver{api}, cfg, now, nil)
+	if err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	detail, err := metricDetail(data, "PVC使用率", "")
+	if err != nil {
+		t.Fatalf("获取下钻数据失败: %v", err)
+	}
+	if detail.Date != "2025-03-02" {
+		t.Errorf("未指定日期时应取最近一天，实际 %s", detail.Date)
+	}
+	if got := detail.Metric.LabelNames; len(got) != 2 || got[0] != "命名空间" || got[1] != "PVC" {
+		t.Errorf("列名不正确: %v", got)
+	}
+	if len(detail.Metric.Series) != 2 {
+		t.Fatalf("期望 2 条序列，实际 %d 条", len(detail.Metric.Series))
+	}
+
+	mysql, redis := detail.Metric.Series[0], detail.Metric.Series[1]
+	if mysql.Labels[1] != "data-mysql-0" || mysql.DailyStatus["2025-03-02"] != threshold.StatusCritical {
+		t.Errorf("mysql PVC 状态不正确: %+v", mysql)
+	}
+	if redis.DailyStatus["2025-03-02"] != threshold.StatusCritical {
+		t.Errorf("redis PVC 应取两个 Pod 中更严重的状态，实际 %s", redis.DailyStatus["2025-03-02"])
+	}
+
+	// 采样点间隔 1 小时，从 0 点开始
+	hourly := mysql.HourlyStatus["2025-03-02"]
+	if hourly[0] != threshold.StatusNormal || hourly[1] != threshold.StatusCritical || hourly[3] != "" {
+		t.Errorf("每小时状态不正确: %v", hourly)
+	}
+	if redis.HourlyStatus["2025-03-02"][1] != threshold.StatusCritical {
+		t.Errorf("合并后的每小时状态不正确: %v", redis.HourlyStatus["2025-03-02"])
+	}
+
+	detail, _ = metricDetail(data, "PVC使用率", "2025-03-01")
+	if detail.Date != "2025-03-01" {
+		t.Errorf("应显示指定日期，实际 %s", detail.Date)
+	}
+
+	if _, err := metricDetail(data, "不存在", ""); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("不存在的指标应返回 ErrMetricNotFound，实际 %v", err)
+	}
+}
+
+func TestCollectMetricStatusDisplayMetric(t *testing.T) {
+	// 展示类指标未配置阈值（0, greater），与报告一致始终为正常
+	cfg := &config.Config{
+		MetricTypes: []config.MetricType{{
+			Type:    "host",
+			Metrics: []config.MetricConfig{{Name: "内存总量", Query: "q_mem_total", Type: config.MetricTypeDisplay, Unit: "GB"}},
+		}},
+	}
+	cfg.Status.Days = 2
+	cfg.Status.Timezone = "UTC"
+
+	api := &fakeAPI{matrices: map[string]model.Matrix{"q_mem_total": {series("a", 16, 16)}}}
+	data, err := collectMetricStatus(fakeResolver{api}, cfg, time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatalf("收集状态失败: %v", err)
+	}
+	metric := data.Metrics[0]
+	for _, date := range data.Dates {
+		if metric.DailyStatus[date] != threshold.StatusNormal {
+			t.Errorf("展示类指标在 %s 应为正常，实际 %s", date, metric.DailyStatus[date])
+		}
+	}
+	if metric.ThresholdText != "仅展示，不做阈值判断" {
+		t.Errorf("展示类指标的阈值说明不正确: %s", metric.ThresholdText)
+	}
+}
@@ -0,0 +1,249 @@
+package threshold
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 标签匹配方式，与 PromQL 相同
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// 规则来源，在报告中显示命中的是哪条规则
+const (
+	SourceDefault = "默认阈值"
+	SourceQuery   = "threshold_query"
+)
+
+// Matcher 单个标签匹配器，如 instance=~"db-.*"
+type Matcher struct {
+	Name  string
+	Op    string
+	Value string
+	re    *regexp.Regexp
+}
+
+// Matches 判断标签集是否满足匹配器，缺失的标签按空字符串处理
+func (m *Matcher) Matches(labels map[string]string) bool {
+	value := labels[m.Name]
+	switch m.Op {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return m.Name + m.Op + strconv.Quote(m.Value)
+}
+
+// Selector 一组同时满足的标签匹配器
+type Selector []*Matcher
+
+// ParseSelector 解析 PromQL 风格的标签选择器，如 `instance=~"db-.*", job="node"`，花括号可省略
+func ParseSelector(s string) (Selector, error) {
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	}
+
+	var selector Selector
+	for rest != "" {
+		nameEnd := strings.IndexFunc(rest, func(r rune) bool {
+			return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		})
+		if nameEnd <= 0 {
+			return nil, fmt.Errorf("invalid label matcher %q", rest)
+		}
+		m := &Matcher{Name: rest[:nameEnd]}
+		rest = strings.TrimSpace(rest[nameEnd:])
+
+		for _, op := range []string{MatchRegexp, MatchNotRegexp, MatchNotEqual, MatchEqual} {
+			if strings.HasPrefix(rest, op) {
+				m.Op = op
+				break
+			}
+		}
+		if m.Op == "" {
+			return nil, fmt.Errorf("label %s: missing operator, expected =, !=, =~ or !~", m.Name)
+		}
+		rest = strings.TrimSpace(rest[len(m.Op):])
+
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil || strings.HasPrefix(quoted, "'") {
+			return nil, fmt.Errorf("label %s: value must be a double-quoted string", m.Name)
+		}
+		m.Value, _ = strconv.Unquote(quoted)
+		rest = strings.TrimSpace(rest[len(quoted):])
+
+		if m.Op == MatchRegexp || m.Op == MatchNotRegexp {
+			// 与 Prometheus 一致，正则需完整匹配
+			m.re, err = regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("label %s: %w", m.Name, err)
+			}
+		}
+		selector = append(selector, m)
+
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, fmt.Errorf("unexpected %q after label %s", rest, m.Name)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("empty label selector")
+	}
+	return selector, nil
+}
+
+// Matches 判断标签集是否满足全部匹配器
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, m := range s {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// specificity 匹配器越多越具体，数量相同时等值匹配比正则更具体
+func (s Selector) specificity() (matchers, equals int) {
+	for _, m := range s {
+		if m.Op == MatchEqual {
+			equals++
+		}
+	}
+	return len(s), equals
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, m := range s {
+		parts[i] = m.String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Override 按标签覆盖指标的阈值
+type Override struct {
+	Match             string   `yaml:"match"`                        // 标签选择器，如 instance=~"db-.*"
+	Threshold         *float64 `yaml:"threshold,omitempty"`          // 覆盖 threshold
+	WarningThreshold  *float64 `yaml:"warning_threshold,omitempty"`  // 覆盖 warning_threshold
+	CriticalThreshold *float64 `yaml:"critical_threshold,omitempty"` // 覆盖 critical_threshold
+	Range             *Range   `yaml:"range,omitempty"`              // 覆盖 between/outside 的区间
+}
+
+// apply 在基础规则上应用覆盖；只要覆盖了任一阈值，基础规则中显式的警告/严重阈值不再沿用
+func (o Override) apply(base Rule) Rule {
+	rule := base
+	if o.Threshold != nil || o.WarningThreshold != nil || o.CriticalThreshold != nil {
+		rule.Warning, rule.Critical = o.WarningThreshold, o.CriticalThreshold
+		if o.Threshold != nil {
+			rule.Threshold = *o.Threshold
+		}
+	}
+	if o.Range != nil {
+		rule.Range = o.Range
+	}
+	return rule
+}
+
+// SeriesThreshold threshold_query 返回的单条序列阈值
+type SeriesThreshold struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// match 阈值序列的标签（忽略 __name__）是指标序列标签的子集时视为匹配，返回参与匹配的标签数
+func (s SeriesThreshold) match(labels map[string]string) (int, bool) {
+	count := 0
+	for name, value := range s.Labels {
+		if name == "__name__" {
+			continue
+		}
+		if labels[name] != value {
+			return 0, false
+		}
+		count++
+	}
+	return count, true
+}
+
+type compiledOverride struct {
+	selector Selector
+	rule     Rule
+}
+
+// RuleSet 指标的全部阈值规则，按序列标签选出最具体的一条
+type RuleSet struct {
+	base      Rule
+	overrides []compiledOverride
+	series    []SeriesThreshold
+}
+
+// NewRuleSet 编译基础规则和标签覆盖，选择器无效时返回错误
+func NewRuleSet(base Rule, overrides []Override) (*RuleSet, error) {
+	set := &RuleSet{base: base}
+	for i, o := range overrides {
+		selector, err := ParseSelector(o.Match)
+		if err != nil {
+			return nil, fmt.Errorf("threshold_overrides[%d]: %w", i, err)
+		}
+		set.overrides = append(set.overrides, compiledOverride{selector: selector, rule: o.apply(base)})
+	}
+	return set, nil
+}
+
+// SetSeriesThresholds 设置 threshold_query 查询到的逐序列阈值
+func (s *RuleSet) SetSeriesThresholds(series []SeriesThreshold) {
+	s.series = series
+}
+
+// For 返回序列适用的规则及其来源。优先级：threshold_query 的逐序列阈值 > 最具体的标签覆盖 > 默认阈值；
+// 具体程度相同时取配置中靠前的一条
+func (s *RuleSet) For(labels map[string]string) (Rule, string) {
+	rule, source := s.base, SourceDefault
+
+	bestMatchers, bestEquals := -1, -1
+	for _, o := range s.overrides {
+		if !o.selector.Matches(labels) {
+			continue
+		}
+		matchers, equals := o.selector.specificity()
+		if matchers > bestMatchers || matchers == bestMatchers && equals > bestEquals {
+			bestMatchers, bestEquals = matchers, equals
+			rule, source = o.rule, o.selector.String()
+		}
+	}
+
+	bestLabels := -1
+	var series *SeriesThreshold
+	for i := range s.series {
+		if count, ok := s.series[i].match(labels); ok && count > bestLabels {
+			bestLabels = count
+			series = &s.series[i]
+		}
+	}
+	if series != nil {
+		rule.Threshold = series.Value
+		rule.Warning, rule.Critical = nil, nil
+		source = SourceQuery
+	}
+	return rule, source
+}
@@ -0,0 +1,92 @@
+package threshold
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	selector, err := ParseSelector(`{instance=~"db-.*", job!="node", env="prod" , host!~"tmp-\\d+"}`)
+	if err != nil {
+		t.Fatalf("解析选择器失败: %v", err)
+	}
+	if len(selector) != 4 {
+		t.Fatalf("期望 4 个匹配器，实际 %d", len(selector))
+	}
+	if got := selector.String(); got != `{instance=~"db-.*", job!="node", env="prod", host!~"tmp-\\d+"}` {
+		t.Errorf("String() = %s", got)
+	}
+
+	matching := map[string]string{"instance": "db-01:9100", "job": "mysql", "env": "prod", "host": "db-01"}
+	if !selector.Matches(matching) {
+		t.Error("应该匹配")
+	}
+	for name, value := range map[string]string{"instance": "app-db-01", "job": "node", "env": "test", "host": "tmp-12"} {
+		labels := map[string]string{}
+		for k, v := range matching {
+			labels[k] = v
+		}
+		labels[name] = value
+		if selector.Matches(labels) {
+			t.Errorf("%s=%s 不应该匹配", name, value)
+		}
+	}
+
+	for _, invalid := range []string{"", "instance", `instance>"a"`, `instance=db`, `instance="a" job="b"`, `instance=~"("`, `instance='a'`} {
+		if _, err := ParseSelector(invalid); err == nil {
+			t.Errorf("%q 应该解析失败", invalid)
+		}
+	}
+}
+
+func TestRuleSetFor(t *testing.T) {
+	base := Rule{Type: Greater, Threshold: 75}
+	set, err := NewRuleSet(base, []Override{
+		{Match: `instance=~"db-.*"`, Threshold: ptr(92)},
+		{Match: `instance=~"db-.*", role="primary"`, WarningThreshold: ptr(85), CriticalThreshold: ptr(95)},
+		{Match: `instance="db-02"`, Threshold: ptr(80)},
+		{Match: `instance=~"db-0.*"`, Threshold: ptr(60)},
+	})
+	if err != nil {
+		t.Fatalf("编译阈值规则失败: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		wantSource string
+		value      float64
+		wantStatus string
+	}{
+		{"未命中覆盖", map[string]string{"instance": "app-01"}, SourceDefault, 90, StatusCritical},
+		{"命中正则覆盖", map[string]string{"instance": "db-01"}, `{instance=~"db-.*"}`, 90, StatusWarning},
+		{"匹配器多的更具体", map[string]string{"instance": "db-01", "role": "primary"}, `{instance=~"db-.*", role="primary"}`, 90, StatusWarning},
+		{"等值匹配比正则更具体", map[string]string{"instance": "db-02"}, `{instance="db-02"}`, 81, StatusCritical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, source := set.For(tt.labels)
+			if source != tt.wantSource {
+				t.Errorf("命中规则 = %s, 期望 %s", source, tt.wantSource)
+			}
+			if got := rule.Evaluate(tt.value); got != tt.wantStatus {
+				t.Errorf("Evaluate(%v) = %s, 期望 %s", tt.value, got, tt.wantStatus)
+			}
+		})
+	}
+
+	// threshold_query 的逐序列阈值优先于标签覆盖，取标签最多的一条
+	set.SetSeriesThresholds([]SeriesThreshold{
+		{Labels: map[string]string{"__name__": "limit", "job": "node"}, Value: 50},
+		{Labels: map[string]string{"job": "node", "instance": "db-01"}, Value: 99},
+	})
+	rule, source := set.For(map[string]string{"instance": "db-01", "job": "node"})
+	if source != SourceQuery || rule.Threshold != 99 {
+		t.Errorf("逐序列阈值不正确: %s %+v", source, rule)
+	}
+	rule, source = set.For(map[string]string{"instance": "db-03", "job": "node"})
+	if source != SourceQuery || rule.Threshold != 50 || rule.Evaluate(45) != StatusWarning {
+		t.Errorf("逐序列阈值不正确: %s %+v", source, rule)
+	}
+
+	if _, err := NewRuleSet(base, []Override{{Match: `instance=~"["`}}); err == nil {
+		t.Error("无效的选择器应该返回错误")
+	}
+}
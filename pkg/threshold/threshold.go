@@ -0,0 +1,233 @@
+package threshold
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 统一的状态取值，报告和健康看板共用
+const (
+	StatusNormal   = "normal"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+)
+
+// severity 状态的严重程度，用于取多个状态中最严重的一个
+var severity = map[string]int{
+	StatusNormal:   0,
+	StatusWarning:  1,
+	StatusCritical: 2,
+}
+
+// Worse 返回两个状态中更严重的一个
+func Worse(a, b string) string {
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
+
+// 阈值比较方式
+const (
+	Greater      = "greater"       // 大于阈值为严重
+	GreaterEqual = "greater_equal" // 大于等于阈值为严重
+	Less         = "less"          // 小于阈值为正常
+	LessEqual    = "less_equal"    // 小于等于阈值为正常
+	Equal        = "equal"         // 等于阈值为正常
+	NotEqual     = "not_equal"     // 不等于阈值为正常
+	AtLeast      = "at_least"      // 大于等于阈值为正常（下限保障）
+	Between      = "between"       // 在 range 范围内为正常
+	Outside      = "outside"       // 在 range 范围外为正常
+)
+
+// 未显式配置 warning_threshold/critical_threshold 时，推算另一侧阈值的比例
+const (
+	upperWarningFactor = 0.8 // greater/greater_equal：达到阈值的 80% 开始警告
+	lessCriticalFactor = 1.2 // less/less_equal：超过阈值的 120% 为严重
+	lowerWarningFactor = 0.8 // at_least：低于阈值的 80% 为严重
+)
+
+// Types 支持的全部比较方式
+var Types = []string{Greater, GreaterEqual, Less, LessEqual, Equal, NotEqual, AtLeast, Between, Outside}
+
+// Valid 判断比较方式是否受支持，空值按 greater 处理
+func Valid(thresholdType string) bool {
+	if thresholdType == "" {
+		return true
+	}
+	for _, t := range Types {
+		if t == thresholdType {
+			return true
+		}
+	}
+	return false
+}
+
+// 阈值方向：值越大越危险、越小越危险，或两侧都可能异常
+const (
+	DirectionUpper = "upper"
+	DirectionLower = "lower"
+	DirectionBoth  = "both"
+)
+
+// Range 区间阈值，用于 between/outside
+type Range struct {
+	Min float64 `yaml:"min" json:"min"`
+	Max float64 `yaml:"max" json:"max"`
+}
+
+// Rule 一条阈值规则
+type Rule struct {
+	Type      string   `json:"type"`
+	Threshold float64  `json:"threshold"`
+	Warning   *float64 `json:"warning,omitempty"`  // 警告阈值，未配置时按比较方式推算
+	Critical  *float64 `json:"critical,omitempty"` // 严重阈值，未配置时使用 Threshold
+	Range     *Range   `json:"range,omitempty"`    // between/outside 使用的区间
+	Display   bool     `json:"display,omitempty"`  // 展示类指标，不做阈值判断，始终为正常
+}
+
+func (r Rule) thresholdType() string {
+	if r.Type == "" {
+		return Greater
+	}
+	return r.Type
+}
+
+// Direction 返回规则的阈值方向：greater 类为上限，less 类和 at_least 为下限，其余为双侧
+func (r Rule) Direction() string {
+	switch r.thresholdType() {
+	case Greater, GreaterEqual:
+		return DirectionUpper
+	case Less, LessEqual, AtLeast:
+		return DirectionLower
+	}
+	return DirectionBoth
+}
+
+// bounds 返回警告阈值和严重阈值，未显式配置的一侧按比例推算
+func (r Rule) bounds() (warning, critical float64) {
+	switch r.thresholdType() {
+	case Less, LessEqual:
+		// 低于 warning 为正常，超过 critical 为严重
+		warning = valueOr(r.Warning, r.Threshold)
+		critical = valueOr(r.Critical, warning*lessCriticalFactor)
+	case AtLeast:
+		// 不低于 warning 为正常，低于 critical 为严重
+		warning = valueOr(r.Warning, r.Threshold)
+		critical = valueOr(r.Critical, warning*lowerWarningFactor)
+	default:
+		// 达到 warning 开始警告，超过 critical 为严重
+		critical = valueOr(r.Critical, r.Threshold)
+		warning = valueOr(r.Warning, critical*upperWarningFactor)
+	}
+	return warning, critical
+}
+
+func valueOr(v *float64, fallback float64) float64 {
+	if v != nil {
+		return *v
+	}
+	return fallback
+}
+
+// Evaluate 按规则判断值的状态
+func (r Rule) Evaluate(value float64) string {
+	if r.Display {
+		return StatusNormal
+	}
+	warning, critical := r.bounds()
+
+	switch r.thresholdType() {
+	case Greater:
+		if value > critical {
+			return StatusCritical
+		} else if value >= warning {
+			return StatusWarning
+		}
+		return StatusNormal
+	case GreaterEqual:
+		if value >= critical {
+			return StatusCritical
+		} else if value >= warning {
+			return StatusWarning
+		}
+		return StatusNormal
+	case Less:
+		if value < warning {
+			return StatusNormal
+		} else if value <= critical {
+			return StatusWarning
+		}
+		return StatusCritical
+	case LessEqual:
+		if value <= warning {
+			return StatusNormal
+		} else if value <= critical {
+			return StatusWarning
+		}
+		return StatusCritical
+	case Equal:
+		if value == r.Threshold {
+			return StatusNormal
+		}
+		return StatusCritical
+	case NotEqual:
+		if value != r.Threshold {
+			return StatusNormal
+		}
+		return StatusCritical
+	case AtLeast:
+		if value >= warning {
+			return StatusNormal
+		} else if value >= critical {
+			return StatusWarning
+		}
+		return StatusCritical
+	case Between:
+		if r.Range == nil || (value >= r.Range.Min && value <= r.Range.Max) {
+			return StatusNormal
+		}
+		return StatusCritical
+	case Outside:
+		if r.Range == nil || value < r.Range.Min || value > r.Range.Max {
+			return StatusNormal
+		}
+		return StatusCritical
+	}
+	return StatusNormal
+}
+
+// Describe 返回规则的简短说明，如 "≥80 警告，>100 严重"
+func (r Rule) Describe(unit string) string {
+	if r.Display {
+		return "仅展示，不做阈值判断"
+	}
+	warning, critical := r.bounds()
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) + unit }
+
+	switch r.thresholdType() {
+	case Greater:
+		return fmt.Sprintf("≥%s 警告，>%s 严重", f(warning), f(critical))
+	case GreaterEqual:
+		return fmt.Sprintf("≥%s 警告，≥%s 严重", f(warning), f(critical))
+	case Less:
+		return fmt.Sprintf("<%s 正常，>%s 严重", f(warning), f(critical))
+	case LessEqual:
+		return fmt.Sprintf("≤%s 正常，>%s 严重", f(warning), f(critical))
+	case Equal:
+		return fmt.Sprintf("=%s 正常", f(r.Threshold))
+	case NotEqual:
+		return fmt.Sprintf("≠%s 正常", f(r.Threshold))
+	case AtLeast:
+		return fmt.Sprintf("≥%s 正常，<%s 严重", f(warning), f(critical))
+	case Between:
+		if r.Range != nil {
+			return fmt.Sprintf("%s ~ %s 正常", f(r.Range.Min), f(r.Range.Max))
+		}
+	case Outside:
+		if r.Range != nil {
+			return fmt.Sprintf("%s ~ %s 之外正常", f(r.Range.Min), f(r.Range.Max))
+		}
+	}
+	return ""
+}
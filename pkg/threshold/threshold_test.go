@@ -0,0 +1,133 @@
+package threshold
+
+import "testing"
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestRuleEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		value float64
+		want  string
+	}{
+		// greater：默认 80% 开始警告
+		{"greater 正常", Rule{Type: Greater, Threshold: 100}, 79, StatusNormal},
+		{"greater 警告下限", Rule{Type: Greater, Threshold: 100}, 80, StatusWarning},
+		{"greater 等于阈值", Rule{Type: Greater, Threshold: 100}, 100, StatusWarning},
+		{"greater 严重", Rule{Type: Greater, Threshold: 100}, 101, StatusCritical},
+		{"空类型按 greater", Rule{Threshold: 100}, 101, StatusCritical},
+
+		// greater_equal
+		{"greater_equal 正常", Rule{Type: GreaterEqual, Threshold: 100}, 79, StatusNormal},
+		{"greater_equal 警告", Rule{Type: GreaterEqual, Threshold: 100}, 99, StatusWarning},
+		{"greater_equal 严重", Rule{Type: GreaterEqual, Threshold: 100}, 100, StatusCritical},
+
+		// less：小于阈值正常，超过 120% 严重
+		{"less 正常", Rule{Type: Less, Threshold: 100}, 99, StatusNormal},
+		{"less 等于阈值", Rule{Type: Less, Threshold: 100}, 100, StatusWarning},
+		{"less 警告上限", Rule{Type: Less, Threshold: 100}, 120, StatusWarning},
+		{"less 严重", Rule{Type: Less, Threshold: 100}, 121, StatusCritical},
+
+		// less_equal
+		{"less_equal 正常", Rule{Type: LessEqual, Threshold: 100}, 100, StatusNormal},
+		{"less_equal 警告", Rule{Type: LessEqual, Threshold: 100}, 110, StatusWarning},
+		{"less_equal 严重", Rule{Type: LessEqual, Threshold: 100}, 130, StatusCritical},
+
+		// equal / not_equal
+		{"equal 正常", Rule{Type: Equal, Threshold: 1}, 1, StatusNormal},
+		{"equal 严重", Rule{Type: Equal, Threshold: 1}, 0, StatusCritical},
+		{"not_equal 正常", Rule{Type: NotEqual, Threshold: 0}, 3, StatusNormal},
+		{"not_equal 严重", Rule{Type: NotEqual, Threshold: 0}, 0, StatusCritical},
+
+		// at_least：不低于阈值正常，低于 80% 严重
+		{"at_least 正常", Rule{Type: AtLeast, Threshold: 3}, 3, StatusNormal},
+		{"at_least 警告", Rule{Type: AtLeast, Threshold: 10}, 8, StatusWarning},
+		{"at_least 严重", Rule{Type: AtLeast, Threshold: 10}, 7, StatusCritical},
+
+		// between / outside
+		{"between 区间内", Rule{Type: Between, Range: &Range{Min: 10, Max: 20}}, 10, StatusNormal},
+		{"between 低于区间", Rule{Type: Between, Range: &Range{Min: 10, Max: 20}}, 9, StatusCritical},
+		{"between 高于区间", Rule{Type: Between, Range: &Range{Min: 10, Max: 20}}, 21, StatusCritical},
+		{"outside 区间外", Rule{Type: Outside, Range: &Range{Min: 10, Max: 20}}, 5, StatusNormal},
+		{"outside 区间内", Rule{Type: Outside, Range: &Range{Min: 10, Max: 20}}, 15, StatusCritical},
+		{"between 未配置区间", Rule{Type: Between}, 15, StatusNormal},
+
+		// 显式的 warning/critical 阈值
+		{"greater 显式警告", Rule{Type: Greater, Threshold: 90, Warning: ptr(70)}, 75, StatusWarning},
+		{"greater 显式严重", Rule{Type: Greater, Warning: ptr(70), Critical: ptr(90)}, 91, StatusCritical},
+		{"greater 仅配置严重", Rule{Type: Greater, Critical: ptr(90)}, 72, StatusWarning},
+		{"less 显式阈值正常", Rule{Type: Less, Warning: ptr(50), Critical: ptr(60)}, 49, StatusNormal},
+		{"less 显式阈值警告", Rule{Type: Less, Warning: ptr(50), Critical: ptr(60)}, 55, StatusWarning},
+		{"less 显式阈值严重", Rule{Type: Less, Warning: ptr(50), Critical: ptr(60)}, 61, StatusCritical},
+		{"at_least 显式阈值警告", Rule{Type: AtLeast, Warning: ptr(5), Critical: ptr(3)}, 4, StatusWarning},
+		{"at_least 显式阈值严重", Rule{Type: AtLeast, Warning: ptr(5), Critical: ptr(3)}, 2, StatusCritical},
+
+		{"未知类型", Rule{Type: "unknown", Threshold: 1}, 100, StatusNormal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Evaluate(tt.value); got != tt.want {
+				t.Errorf("Evaluate(%v) = %s, 期望 %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, thresholdType := range append(Types, "") {
+		if !Valid(thresholdType) {
+			t.Errorf("%q 应该是合法的比较方式", thresholdType)
+		}
+	}
+	if Valid("greater_than") {
+		t.Error("greater_than 不应该是合法的比较方式")
+	}
+}
+
+func TestRuleDescribe(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Type: Greater, Threshold: 80}, "≥64% 警告，>80% 严重"},
+		{Rule{Type: Less, Warning: ptr(50), Critical: ptr(60)}, "<50% 正常，>60% 严重"},
+		{Rule{Type: Between, Range: &Range{Min: 10, Max: 20}}, "10% ~ 20% 正常"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Describe("%"); got != tt.want {
+			t.Errorf("Describe() = %q, 期望 %q", got, tt.want)
+		}
+	}
+}
+
+func TestRuleDirection(t *testing.T) {
+	tests := map[string]string{
+		Greater:      DirectionUpper,
+		GreaterEqual: DirectionUpper,
+		Less:         DirectionLower,
+		LessEqual:    DirectionLower,
+		AtLeast:      DirectionLower,
+		Equal:        DirectionBoth,
+		Between:      DirectionBoth,
+		Outside:      DirectionBoth,
+		"":           DirectionUpper,
+	}
+	for thresholdType, want := range tests {
+		if got := (Rule{Type: thresholdType}).Direction(); got != want {
+			t.Errorf("%q 的方向为 %s, 期望 %s", thresholdType, got, want)
+		}
+	}
+}
+
+func TestDisplayRuleAlwaysNormal(t *testing.T) {
+	rule := Rule{Type: Greater, Display: true}
+	for _, value := range []float64{-1, 0, 1e12} {
+		if got := rule.Evaluate(value); got != StatusNormal {
+			t.Errorf("展示类规则 %v 应为正常，实际 %s", value, got)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 单位类别
+const (
+	FamilyBytes = "bytes"
+	FamilyRate  = "rate"
+	FamilyTime  = "time"
+	FamilyRatio = "ratio"
+)
+
+// 格式化类型，对应 MetricConfig.FormatType
+const (
+	FormatRate    = "rate"
+	FormatBytes   = "bytes"
+	FormatNumber  = "number"
+	FormatTime    = "time"
+	FormatPercent = "percent"
+)
+
+// unitDef 单位定义：所属类别及换算到基础单位（B、B/s、秒、比例）的系数
+type unitDef struct {
+	family string
+	factor float64
+}
+
+const (
+	kib = 1024
+	mib = 1024 * kib
+	gib = 1024 * mib
+	tib = 1024 * gib
+	pib = 1024 * tib
+)
+
+var unitTable = map[string]unitDef{
+	// 字节，按 1024 进制换算
+	"b":   {FamilyBytes, 1},
+	"kb":  {FamilyBytes, kib},
+	"kib": {FamilyBytes, kib},
+	"mb":  {FamilyBytes, mib},
+	"mib": {FamilyBytes, mib},
+	"gb":  {FamilyBytes, gib},
+	"gib": {FamilyBytes, gib},
+	"tb":  {FamilyBytes, tib},
+	"tib": {FamilyBytes, tib},
+	"pb":  {FamilyBytes, pib},
+	"pib": {FamilyBytes, pib},
+
+	// 速率
+	"b/s":   {FamilyRate, 1},
+	"kb/s":  {FamilyRate, kib},
+	"kib/s": {FamilyRate, kib},
+	"mb/s":  {FamilyRate, mib},
+	"mib/s": {FamilyRate, mib},
+	"gb/s":  {FamilyRate, gib},
+	"gib/s": {FamilyRate, gib},
+	"tb/s":  {FamilyRate, tib},
+	"tib/s": {FamilyRate, tib},
+
+	// 时间
+	"ns":  {FamilyTime, 1e-9},
+	"us":  {FamilyTime, 1e-6},
+	"µs":  {FamilyTime, 1e-6},
+	"ms":  {FamilyTime, 1e-3},
+	"s":   {FamilyTime, 1},
+	"m":   {FamilyTime, 60},
+	"min": {FamilyTime, 60},
+	"h":   {FamilyTime, 3600},
+	"d":   {FamilyTime, 86400},
+
+	// 比例与百分比
+	"ratio":   {FamilyRatio, 1},
+	"%":       {FamilyRatio, 0.01},
+	"percent": {FamilyRatio, 0.01},
+}
+
+func lookup(unit string) (unitDef, bool) {
+	def, ok := unitTable[strings.ToLower(strings.TrimSpace(unit))]
+	return def, ok
+}
+
+// Family 返回单位所属类别，未知单位返回空字符串
+func Family(unit string) string {
+	def, ok := lookup(unit)
+	if !ok {
+		return ""
+	}
+	return def.family
+}
+
+// Convert 在同一类别的单位之间换算数值
+func Convert(value float64, from, to string) (float64, error) {
+	fromDef, ok := lookup(from)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toDef, ok := lookup(to)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromDef.family != toDef.family {
+		return 0, fmt.Errorf("cannot convert %q to %q", from, to)
+	}
+	return value * fromDef.factor / toDef.factor, nil
+}
+
+// Scale 应用缩放因子，未配置时原样返回
+func Scale(value float64, factor *float64) float64 {
+	if factor == nil {
+		return value
+	}
+	return value * *factor
+}
+
+// Format 根据源单位、目标单位和格式化类型生成显示字符串
+func Format(value float64, unit, targetUnit, formatType string) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Sprintf("%v", value)
+	}
+
+	// 指定了目标单位：换算后按目标单位显示
+	if targetUnit != "" && targetUnit != "auto" {
+		converted, err := Convert(value, unit, targetUnit)
+		if err != nil {
+			return fmt.Sprintf("%.2f%s", value, unit)
+		}
+		return fmt.Sprintf("%.2f%s", converted, targetUnit)
+	}
+
+	switch formatType {
+	case FormatBytes:
+		return FormatBytesValue(toBase(value, unit, FamilyBytes))
+	case FormatRate:
+		return FormatRateValue(toBase(value, unit, FamilyRate))
+	case FormatTime:
+		return FormatDuration(toBase(value, unit, FamilyTime))
+	case FormatPercent:
+		if Family(unit) == FamilyRatio {
+			value, _ = Convert(value, unit, "%")
+		}
+		return fmt.Sprintf("%.2f%%", value)
+	case FormatNumber:
+		return FormatNumberValue(value) + unit
+	}
+
+	return fmt.Sprintf("%.2f%s", value, unit)
+}
+
+// ToBase 将数值换算为所属类别的基础单位（B、B/s、秒），单位未知时原样返回
+func ToBase(value float64, unit string) float64 {
+	def, ok := lookup(unit)
+	if !ok {
+		return value
+	}
+	return value * def.factor
+}
+
+// toBase 在单位属于指定类别时换算为基础单位，否则视为已是基础单位
+func toBase(value float64, unit, family string) float64 {
+	if def, ok := lookup(unit); ok && def.family == family {
+		return value * def.factor
+	}
+	return value
+}
+
+// FormatBytesValue 将字节数格式化为 B/KB/MB/GB/TB
+func FormatBytesValue(bytes float64) string {
+	if bytes == 0 {
+		return "0 B"
+	}
+	value, unit := autoScale(bytes, []string{"B", "KB", "MB", "GB", "TB", "PB"})
+	return fmt.Sprintf("%.2f %s", value, unit)
+}
+
+// FormatRateValue 将每秒字节数格式化为 B/s、KB/s、MB/s 等
+func FormatRateValue(bytesPerSecond float64) string {
+	if bytesPerSecond == 0 {
+		return "0 B/s"
+	}
+	value, unit := autoScale(bytesPerSecond, []string{"B/s", "KB/s", "MB/s", "GB/s", "TB/s"})
+	return fmt.Sprintf("%.2f %s", value, unit)
+}
+
+func autoScale(value float64, unitNames []string) (float64, string) {
+	index := 0
+	for math.Abs(value) >= 1024 && index < len(unitNames)-1 {
+		value /= 1024
+		index++
+	}
+	return value, unitNames[index]
+}
+
+// FormatDuration 将秒数格式化为“x天x小时x分钟”形式
+func FormatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return "0秒"
+	}
+	if seconds < 1 {
+		return fmt.Sprintf("%.2f毫秒", seconds*1000)
+	}
+
+	days := int(seconds) / 86400
+	hours := (int(seconds) % 86400) / 3600
+	minutes := (int(seconds) % 3600) / 60
+	secs := int(seconds) % 60
+
+	if days > 0 {
+		if hours > 0 {
+			return fmt.Sprintf("%d天%d小时%d分钟", days, hours, minutes)
+		}
+		return fmt.Sprintf("%d天%d分钟", days, minutes)
+	} else if hours > 0 {
+		return fmt.Sprintf("%d小时%d分钟", hours, minutes)
+	} else if minutes > 0 {
+		return fmt.Sprintf("%d分钟%d秒", minutes, secs)
+	}
+	return fmt.Sprintf("%d秒", secs)
+}
+
+// FormatNumberValue 添加千位分隔符，非整数保留两位小数
+func FormatNumberValue(number float64) string {
+	sign := ""
+	if number < 0 {
+		sign = "-"
+		number = -number
+	}
+
+	var str, fraction string
+	if number == math.Trunc(number) {
+		str = fmt.Sprintf("%.0f", number)
+	} else {
+		formatted := fmt.Sprintf("%.2f", number)
+		dot := strings.IndexByte(formatted, '.')
+		str, fraction = formatted[:dot], formatted[dot:]
+	}
+
+	if len(str) <= 3 {
+		return sign + str + fraction
+	}
+
+	var result []rune
+	for i, r := range str {
+		if i > 0 && (len(str)-i)%3 == 0 {
+			result = append(result, ',')
+		}
+		result = append(result, r)
+	}
+	return sign + string(result) + fraction
+}
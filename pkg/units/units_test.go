@@ -0,0 +1,78 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{1024, "B", "KB", 1, false},
+		{1, "GiB", "MiB", 1024, false},
+		{3 * 1024 * 1024, "B/s", "MB/s", 3, false},
+		{90, "s", "min", 1.5, false},
+		{2, "h", "s", 7200, false},
+		{0.85, "ratio", "%", 85, false},
+		{42, "%", "ratio", 0.42, false},
+		{1, "B", "s", 0, true},
+		{1, "unknown", "B", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Convert(%v, %q, %q) 错误 = %v, 期望错误 %v", tt.value, tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %q, %q) = %v, 期望 %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      float64
+		unit       string
+		targetUnit string
+		formatType string
+		want       string
+	}{
+		{"默认格式", 12.345, "%", "", "", "12.35%"},
+		{"字节自动换算", 1536, "B", "", FormatBytes, "1.50 KB"},
+		{"MB为源单位的字节", 2048, "MB", "", FormatBytes, "2.00 GB"},
+		{"速率自动换算", 5 * 1024 * 1024, "B/s", "", FormatRate, "5.00 MB/s"},
+		{"旧配置MB/s速率", 0.5, "MB/s", "", FormatRate, "512.00 KB/s"},
+		{"时间格式", 90061, "s", "", FormatTime, "1天1小时1分钟"},
+		{"毫秒转时间", 1500, "ms", "", FormatTime, "1秒"},
+		{"比例转百分比", 0.256, "ratio", "", FormatPercent, "25.60%"},
+		{"数字千位分隔", 1234567, "个", "", FormatNumber, "1,234,567个"},
+		{"指定目标单位", 1073741824, "B", "GiB", "", "1.00GiB"},
+		{"目标单位不兼容", 10, "B", "s", "", "10.00B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(tt.value, tt.unit, tt.targetUnit, tt.formatType)
+			if got != tt.want {
+				t.Errorf("Format(%v, %q, %q, %q) = %q, 期望 %q", tt.value, tt.unit, tt.targetUnit, tt.formatType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScale(t *testing.T) {
+	if got := Scale(10, nil); got != 10 {
+		t.Errorf("未配置缩放因子时应返回原值，实际 %v", got)
+	}
+	factor := 0.001
+	if got := Scale(2000, &factor); got != 2 {
+		t.Errorf("Scale(2000, 0.001) = %v, 期望 2", got)
+	}
+}
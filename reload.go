@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"PromAI/pkg/api"
+	"PromAI/pkg/config"
+	"PromAI/pkg/jobs"
+	"PromAI/pkg/metrics"
+	"PromAI/pkg/report"
+	"PromAI/pkg/status"
+
+	"github.com/robfig/cron/v3"
+)
+
+// service 当前生效的配置及由其构建的组件。重新加载配置时先完整构建新的组件，
+// 校验和构建都成功后才整体替换，失败时继续使用旧配置
+type service struct {
+	configPath      string
+	validateOptions config.ValidateOptions
+	manager         *jobs.Manager
+	api             *api.Server
+
+	reloadMu sync.Mutex // 同一时刻只执行一次重新加载
+	current  atomic.Pointer[runtime]
+}
+
+// runtime 由一份配置构建的组件
+type runtime struct {
+	config      *config.Config
+	collector   *metrics.Collector
+	statusCache *status.Cache
+	crons       []*cron.Cron
+}
+
+func (s *service) config() *config.Config {
+	return s.current.Load().config
+}
+
+func (s *service) collector() *metrics.Collector {
+	return s.current.Load().collector
+}
+
+func (s *service) statusCache() *status.Cache {
+	return s.current.Load().statusCache
+}
+
+// load 读取配置文件并构建全部组件，定时任务和健康看板刷新尚未启动
+func (s *service) load() (*runtime, error) {
+	collector, config, err := setup(s.configPath, s.validateOptions)
+	if err != nil {
+		return nil, err
+	}
+	crons, err := s.buildCrons(config)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime{
+		config:      config,
+		collector:   collector,
+		statusCache: status.NewCache(collector, config),
+		crons:       crons,
+	}, nil
+}
+
+// activate 替换当前生效的组件，停止旧的定时任务和健康看板刷新，启动新的
+func (s *service) activate(rt *runtime) {
+	applyGlobals(rt.config)
+	old := s.current.Swap(rt)
+	if s.api != nil {
+		s.api.SetConfig(rt.config)
+	}
+
+	if old != nil {
+		for _, c := range old.crons {
+			c.Stop()
+		}
+		// 新旧缓存共用 cache_dir，等待旧缓存进行中的刷新写完缓存文件后再启动新的
+		old.statusCache.Stop()
+	}
+	for _, c := range rt.crons {
+		c.Start()
+	}
+	rt.statusCache.Start()
+}
+
+// reload 重新加载配置文件，source 为触发来源，仅用于日志
+func (s *service) reload(source string) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	log.Printf("重新加载配置（%s）: %s", source, s.configPath)
+	rt, err := s.load()
+	if err != nil {
+		log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+		return err
+	}
+	s.activate(rt)
+	log.Printf("配置已重新加载（%s）", source)
+	return nil
+}
+
+// buildCrons 按配置创建巡检和报告清理的定时任务
+func (s *service) buildCrons(config *config.Config) ([]*cron.Cron, error) {
+	var crons []*cron.Cron
+
+	if config.CronSchedule != "" {
+		c := cron.New()
+		_, err := c.AddFunc(config.CronSchedule, func() {
+			s.manager.Submit("cron", func(job jobs.Job) {
+				if job.Status != jobs.StatusSucceeded {
+					log.Printf("定时任务生成报告失败: %s", job.Error)
+					return
+				}
+				log.Printf("定时任务成功生成报告: %s", job.ReportFile)
+				// 按任务完成时生效的配置发送通知
+				sendNotifications(s.config(), job.ReportFile)
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("设置定时任务失败: %w", err)
+		}
+		crons = append(crons, c)
+		log.Printf("定时任务执行计划: %s", config.CronSchedule)
+	} else {
+		log.Printf("未配置定时任务，请手动触发生成报告")
+	}
+
+	if config.ReportCleanup.Enabled {
+		// 确定使用哪个计划
+		cleanupSchedule := config.ReportCleanup.CronSchedule
+		if cleanupSchedule == "" {
+			cleanupSchedule = config.CronSchedule
+		}
+
+		if cleanupSchedule != "" {
+			maxAge := config.ReportCleanup.MaxAge
+			c := cron.New()
+			_, err := c.AddFunc(cleanupSchedule, func() {
+				if err := report.CleanupReports(maxAge); err != nil {
+					log.Printf("报告清理失败: %v", err)
+					return
+				}
+				log.Printf("报告清理成功")
+			})
+			if err != nil {
+				return nil, fmt.Errorf("设置清理定时任务失败: %w", err)
+			}
+			crons = append(crons, c)
+			log.Printf("清理定时任务执行计划: %s", cleanupSchedule)
+		} else {
+			log.Printf("未配置任何定时任务计划，请手动清理报告")
+		}
+	}
+
+	return crons, nil
+}
+
+// watch 按 interval 轮询配置文件内容，变化后重新加载。Kubernetes 通过替换符号链接更新 ConfigMap，
+// 比较文件内容比监听文件事件更可靠
+func (s *service) watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	last, err := fileSum(s.configPath)
+	if err != nil {
+		log.Printf("读取配置文件失败: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sum, err := fileSum(s.configPath)
+			if err != nil {
+				log.Printf("读取配置文件失败: %v", err)
+				continue
+			}
+			if sum == last {
+				continue
+			}
+			last = sum
+			s.reload("配置文件变化")
+		}
+	}()
+	log.Printf("已启动配置文件监听，间隔: %v", interval)
+}
+
+// handleSignals 收到 SIGHUP 时重新加载配置
+func (s *service) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			s.reload("SIGHUP")
+		}
+	}()
+}
+
+// fileSum 返回文件内容的摘要
+func fileSum(path string) ([sha256.Size]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(content), nil
+}